@@ -1,6 +1,8 @@
 package constants
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 )
 
@@ -27,11 +29,18 @@ var (
 
 // Constants for package specific query order.
 var (
-	PackageSortByCols       = []string{"created", "downloads", "updated", "name", "id"}
-	PackageSortByDbColsMap  = []string{"published_at", "downloads", "last_released_at", "name", "id"}
+	PackageSortByCols       = []string{"created", "downloads", "updated", "name", "id", "dependents"}
+	PackageSortByDbColsMap  = []string{"published_at", "downloads", "last_released_at", "name", "id", "dependents_count"}
 	PackageDefaultSortByCol = PackageSortByCols[0]
 )
 
+// Constants for the query order of the dependents of a package.
+var (
+	PackageDependentsSortByCols       = []string{"downloads", "name"}
+	PackageDependentsSortByDbColsMap  = []string{"downloads", "name"}
+	PackageDependentsDefaultSortByCol = PackageDependentsSortByCols[0]
+)
+
 // Constants for sorting.
 var (
 	SortOrders       = []string{"ASC", "DESC"}
@@ -40,7 +49,9 @@ var (
 
 // Error constants
 var (
-	ErrInternalServer = errors.New("Internal server error occurred")
+	ErrInternalServer         = errors.New("Internal server error occurred")
+	ErrUsernameTaken          = errors.New("Username is already taken")
+	ErrUsernameChangeCooldown = errors.Errorf("Username can be changed only once in every %d days", UsernameChangeCooldownDays)
 )
 
 // PackageNameMaxLength is the maximum allowed length of a package.
@@ -50,17 +61,283 @@ const PackageNameMaxLength = 300
 // while registering new package.
 const TempFileNamePrefixForPackageRegister = "gopx-pkg-upload-data-"
 
-// PackageDataMaxSize is the maximum size of a package allowed in GoPx registry.
+// PackageDataMaxSize is the default maximum size of a package allowed in GoPx registry,
+// it is used when no maximum size is configured.
 const PackageDataMaxSize = int64(100 * 1024 * 1024)
 
+// PackageUploadOverheadMaxSize is the maximum allowed size of the multipart framing
+// and of the other form fields in a package uploading request.
+const PackageUploadOverheadMaxSize = int64(1024 * 1024)
+
+// Default limits on the contents of an uploaded package archive.
+const (
+	ArchiveMaxUncompressedSize = int64(500 * 1024 * 1024)
+	ArchiveMaxEntries          = 10000
+	ArchiveMaxCompressionRatio = int64(100)
+	ArchiveMaxPathDepth        = 32
+)
+
+// ArchiveCompressionRatioMinSize is the uncompressed size of an archive from which
+// the compression ratio limit is enforced.
+const ArchiveCompressionRatioMinSize = int64(10 * 1024 * 1024)
+
 // PackageMetaFileNames holds the possible file names consisting of GoPx package metadata.
 var PackageMetaFileNames = []string{"gopx.json", "gopx.yaml", "gopx.yml"}
 
+// PackageMetaFieldAliases holds the accepted keys of each field of the package metadata,
+// keyed by the canonical key. The keys are matched case-insensitively, ignoring '-' and '_'.
+var PackageMetaFieldAliases = map[string][]string{
+	"name":         {"name"},
+	"version":      {"version"},
+	"description":  {"description", "desc"},
+	"homepage":     {"homepage", "homepageurl", "website"},
+	"tags":         {"tags", "keywords"},
+	"license":      {"license", "licence"},
+	"bugsURL":      {"bugsurl", "bugs", "issues", "issuesurl"},
+	"repository":   {"repository", "repositoryurl", "repo"},
+	"docs":         {"docs", "docsurl", "documentation", "documentationurl"},
+	"engines":      {"engines"},
+	"os":           {"os"},
+	"commands":     {"commands", "scripts"},
+	"dependencies": {"dependencies", "deps"},
+}
+
+// Kinds of the dependencies of a package.
+const (
+	DependencyKindGoPx   = "gopx"
+	DependencyKindModule = "module"
+)
+
+// PackageImportPathPrefix is the prefix of the import paths of GoPx packages,
+// followed by the package name.
+const PackageImportPathPrefix = "gopx.io/pkg/"
+
+// Kinds of the imports of the Go sources of a package.
+const (
+	ImportKindStandard = "std"
+	ImportKindGoPx     = DependencyKindGoPx
+	ImportKindModule   = DependencyKindModule
+)
+
+// PackageDependenciesMaxCount is the maximum allowed number of dependencies of a package version.
+const PackageDependenciesMaxCount = 200
+
+// DependencyGraphMaxSize is the maximum number of package versions in a resolved dependency graph.
+const DependencyGraphMaxSize = 1000
+
+// Constants related to the commands of a package version.
+const (
+	PackageCommandsMaxCount      = 50
+	PackageCommandNameMaxLength  = 64
+	PackageCommandValueMaxLength = 1024
+)
+
 // PackageUploadParamName is the param name should be given in package uploading request.
 const PackageUploadParamName = "data"
 
 // ReadmeFileNames holds the possible file names of package README.
 var ReadmeFileNames = []string{"README.md", "README.MD", "readme.md", "ReadMe.md", "README", "readme"}
 
+// LicenseFileNames holds the possible file names of package LICENSE.
+var LicenseFileNames = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "LICENCE.md", "LICENCE.txt", "license", "license.md", "license.txt", "COPYING", "COPYING.md", "COPYING.txt"}
+
+// LicenseFilePrefixes holds the upper case prefixes of the other file names of package LICENSE e.g. LICENSE-MIT.
+var LicenseFilePrefixes = []string{"LICENSE", "LICENCE", "COPYING"}
+
+// ChangelogFileNames holds the possible file names of package CHANGELOG.
+var ChangelogFileNames = []string{"CHANGELOG.md", "CHANGELOG", "CHANGELOG.txt", "changelog.md", "CHANGES.md", "CHANGES", "HISTORY.md", "HISTORY", "HISTORY.txt", "history.md"}
+
+// ChangelogFilePrefixes holds the upper case prefixes of the other file names of package CHANGELOG.
+var ChangelogFilePrefixes = []string{"CHANGELOG", "HISTORY"}
+
+// ReleaseNotesExcerptMaxLength is the maximum length of the release notes excerpt of a version.
+const ReleaseNotesExcerptMaxLength = 200
+
+// Version bumps required by the API changes of a package, ordered by their size.
+const (
+	APIChangePatch = "patch"
+	APIChangeMinor = "minor"
+	APIChangeMajor = "major"
+)
+
+// APIChanges holds the version bumps ordered by their size.
+var APIChanges = []string{APIChangePatch, APIChangeMinor, APIChangeMajor}
+
+// Semver policies of a package, which decide what happens when the version bump
+// of a release is smaller than its API changes require.
+const (
+	SemverPolicyOff    = "off"
+	SemverPolicyWarn   = "warn"
+	SemverPolicyReject = "reject"
+)
+
+// SemverPolicies holds the possible semver policies of a package.
+var SemverPolicies = []string{SemverPolicyOff, SemverPolicyWarn, SemverPolicyReject}
+
+// DefaultSemverPolicy is the semver policy of a newly published package.
+const DefaultSemverPolicy = SemverPolicyWarn
+
+// Build policies of a package, which decide what happens when the Go sources of a
+// release do not type-check.
+const (
+	BuildPolicyOff    = "off"
+	BuildPolicyWarn   = "warn"
+	BuildPolicyReject = "reject"
+)
+
+// BuildPolicies holds the possible build policies of a package.
+var BuildPolicies = []string{BuildPolicyOff, BuildPolicyWarn, BuildPolicyReject}
+
+// DefaultBuildPolicy is the build policy of a newly published package.
+const DefaultBuildPolicy = BuildPolicyWarn
+
+// Build statuses of a package version.
+const (
+	BuildStatusPassed  = "passed"
+	BuildStatusFailed  = "failed"
+	BuildStatusSkipped = "skipped"
+)
+
+// BuildDiagnosticsMaxCount is the maximum number of diagnostics recorded for a package version.
+const BuildDiagnosticsMaxCount = 50
+
+// Kinds of the exported symbols of a package.
+const (
+	SymbolKindConst  = "const"
+	SymbolKindVar    = "var"
+	SymbolKindFunc   = "func"
+	SymbolKindType   = "type"
+	SymbolKindMethod = "method"
+)
+
+// Types of the nodes of the file tree of a package version.
+const (
+	FileTypeFile = "file"
+	FileTypeDir  = "dir"
+)
+
+// Statuses of the files which differ between two versions of a package.
+const (
+	FileChangeAdded    = "added"
+	FileChangeRemoved  = "removed"
+	FileChangeModified = "modified"
+)
+
+// CompareMaxFiles is the maximum number of changed files returned by the comparison of two versions.
+const CompareMaxFiles = 1000
+
+// CompareDiffFileMaxSize is the maximum size of a file whose unified diff is computed.
+const CompareDiffFileMaxSize = int64(1024 * 1024)
+
+// CompareDiffMaxTotalSize is the maximum total size of the unified diffs returned by
+// the comparison of two versions, the diffs beyond it are omitted.
+const CompareDiffMaxTotalSize = 4 * 1024 * 1024
+
+// CompareDiffMaxInputSize is the maximum total size of the file contents which are read
+// and diffed by a single comparison of two versions, the diffs beyond it are omitted.
+// The cached diffs do not count.
+const CompareDiffMaxInputSize = int64(8 * 1024 * 1024)
+
+// ArchiveSourcesMaxSize is the maximum total size of the Go source files of a package
+// archive which are kept parsed in memory for extracting the API of the package.
+const ArchiveSourcesMaxSize = int64(50 * 1024 * 1024)
+
+// GoModFileName is the file name of the go.mod file of a package.
+const GoModFileName = "go.mod"
+
+// ArchiveCapturedFileMaxSize is the maximum allowed size of the metadata, LICENSE and
+// go.mod files in a package archive, since those are kept in memory. The larger Go
+// source files are not parsed, and the larger README, CHANGELOG and the files only
+// matching a LICENSE prefix e.g. LICENSE-THIRD-PARTY are not captured.
+const ArchiveCapturedFileMaxSize = int64(1024 * 1024)
+
 // DefaultReadmeFileName is the default file name of package README.
 var DefaultReadmeFileName = ReadmeFileNames[0]
+
+// UsernameMaxLength is the maximum allowed length of an username.
+const UsernameMaxLength = 39
+
+// UsernameChangeCooldownDays is the minimum number of days between two
+// consecutive username changes of an user.
+const UsernameChangeCooldownDays = 30
+
+// UsernameChangeCooldown is the minimum duration between two consecutive
+// username changes of an user.
+const UsernameChangeCooldown = time.Hour * 24 * UsernameChangeCooldownDays
+
+// Constants for the states of a package.
+const (
+	PackageStatusPending  = "pending"
+	PackageStatusActive   = "active"
+	PackageStatusDeleting = "deleting"
+)
+
+// Constants for the states of a package version and of its outbox entries.
+// A version moves from pending (stored in database only) to stored (accepted by
+// vcs registry) to committed (visible as a release), or to failed when it is
+// compensated after exhausting all retries.
+const (
+	VersionStatusPending   = "pending"
+	VersionStatusStored    = "stored"
+	VersionStatusCommitted = "committed"
+	VersionStatusFailed    = "failed"
+)
+
+// Constants for the operations recorded in the package outbox.
+const (
+	OutboxOperationRegister  = "register"
+	OutboxOperationDelete    = "delete"
+	OutboxOperationSyncOwner = "sync_owner"
+)
+
+// Constants for the package outbox reconciler.
+const (
+	OutboxReconcileInterval = time.Second * 30
+	OutboxLeaseDuration     = time.Minute * 5
+	OutboxMaxAttempts       = 10
+	OutboxMaxBackoff        = time.Hour
+	OutboxBatchSize         = 100
+)
+
+// Constants for the stages of a package publish.
+const (
+	PublishStageQueued     = "queued"
+	PublishStageValidating = "validating"
+	PublishStagePublishing = "publishing"
+	PublishStageCompleted  = "completed"
+)
+
+// Constants for the states of an asynchronous publish job.
+const (
+	PublishJobStatusQueued    = "queued"
+	PublishJobStatusRunning   = "running"
+	PublishJobStatusSucceeded = "succeeded"
+	PublishJobStatusFailed    = "failed"
+)
+
+// Constants for the asynchronous publish job workers.
+const (
+	PublishJobWorkers   = 4
+	PublishJobQueueSize = 100
+)
+
+// Constants for idempotent requests. A key which is still processing after the
+// processing timeout, longer than any request may take, was left by a crash and
+// can be taken over by a retry.
+const (
+	IdempotencyKeyHeader            = "Idempotency-Key"
+	IdempotencyKeyMaxLength         = 255
+	IdempotencyKeyTTL               = time.Hour * 24
+	IdempotencyKeyCleanInterval     = time.Hour
+	IdempotencyKeyProcessingTimeout = time.Minute * 15
+)
+
+// Constants for the states of an idempotency key.
+const (
+	IdempotencyKeyStatusProcessing = "processing"
+	IdempotencyKeyStatusCompleted  = "completed"
+)
+
+// TempFileNamePrefixForIdempotentRequest is the prefix for temp file name
+// while spooling the body of an idempotent request.
+const TempFileNamePrefixForIdempotentRequest = "gopx-idempotent-request-"
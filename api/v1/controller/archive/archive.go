@@ -0,0 +1,462 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/config"
+	"gopx.io/gopx-common/arr"
+)
+
+// Limits holds the limits enforced on the contents of a package archive.
+type Limits struct {
+	MaxUncompressedSize int64
+	MaxEntries          int
+	MaxCompressionRatio int64
+	MaxPathDepth        int
+}
+
+// ConfiguredLimits returns the archive limits from the upload configuration,
+// falling back to the defaults for the limits which are not configured.
+func ConfiguredLimits() *Limits {
+	lim := &Limits{
+		MaxUncompressedSize: constants.ArchiveMaxUncompressedSize,
+		MaxEntries:          constants.ArchiveMaxEntries,
+		MaxCompressionRatio: constants.ArchiveMaxCompressionRatio,
+		MaxPathDepth:        constants.ArchiveMaxPathDepth,
+	}
+
+	cfg := config.Upload.Archive
+	if cfg.MaxUncompressedSize > 0 {
+		lim.MaxUncompressedSize = cfg.MaxUncompressedSize
+	}
+	if cfg.MaxEntries > 0 {
+		lim.MaxEntries = cfg.MaxEntries
+	}
+	if cfg.MaxCompressionRatio > 0 {
+		lim.MaxCompressionRatio = cfg.MaxCompressionRatio
+	}
+	if cfg.MaxPathDepth > 0 {
+		lim.MaxPathDepth = cfg.MaxPathDepth
+	}
+
+	return lim
+}
+
+// File holds a regular file of the package archive, the mode holds the permission bits.
+type File struct {
+	Path   string
+	Size   int64
+	Mode   int64
+	SHA256 string
+}
+
+// Inspection holds everything learnt about a package archive in a single pass.
+// The captured files are looked up at the root of the archive, the empty
+// file name means the file is not found. The optional files and the prefix
+// matches which are not captured for their size are listed in SkippedFiles.
+// Imports holds the sorted import paths of the Go source files, the files which
+// could not be parsed are listed in UnparsedFiles. The parsed source files are kept
+// in Sources keyed by their directories, unless their total size exceeds the limit
+// in which case SourcesTruncated is set.
+type Inspection struct {
+	Invalid           []*types.ArchiveEntryError
+	MetaFileName      string
+	MetaContent       []byte
+	ReadmeFileName    string
+	ReadmeContent     []byte
+	LicenseFileName   string
+	LicenseContent    []byte
+	ChangelogFileName string
+	ChangelogContent  []byte
+	GoModFileName     string
+	GoModContent      []byte
+	SkippedFiles      []string
+	Files             []*File
+	Imports           []string
+	UnparsedFiles     []string
+	FileSet           *token.FileSet
+	Sources           map[string][]*ast.File
+	SourcesTruncated  bool
+	Entries           int
+	CompressedSize    int64
+	UncompressedSize  int64
+	SHA256            string
+	SHA512            string
+}
+
+// captured tracks a root level file which should be kept in memory, the names
+// are ordered by their priority. The files whose upper case names start with one of
+// the prefixes are captured with the lowest priority, if none of the names is found.
+// The optional files and the prefix matches exceeding the size limit are skipped instead
+// of being rejected, so that the lookup goes on for the names.
+type captured struct {
+	names    []string
+	prefixes []string
+	optional bool
+	idx      int
+	name     *string
+	content  *[]byte
+}
+
+// Inspect reads the .tar.gz archive once and checks every entry against the limits,
+// meanwhile it captures the metadata file, README, LICENSE, CHANGELOG and go.mod, builds the file manifest,
+// parses the Go source files and computes the SHA-256 and SHA-512
+// digests of the archive. Only regular files and
+// directories with relative paths inside the archive are allowed.
+// The inspection stops at the first exceeded size, count or ratio limit since the rest of
+// the archive is not worth reading, in that case the sizes and the digests are incomplete.
+// A non-nil error means the archive could not be read at all.
+func Inspect(data io.Reader, lim *Limits) (ins *Inspection, err error) {
+	ins = &Inspection{
+		FileSet: token.NewFileSet(),
+		Sources: map[string][]*ast.File{},
+	}
+
+	hash256 := sha256.New()
+	hash512 := sha512.New()
+	cr := &countingReader{r: io.TeeReader(data, io.MultiWriter(hash256, hash512))}
+
+	gzr, err := gzip.NewReader(cr)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the gzip stream")
+		return nil, err
+	}
+	defer gzr.Close()
+
+	captures := []*captured{
+		{names: constants.PackageMetaFileNames, idx: -1, name: &ins.MetaFileName, content: &ins.MetaContent},
+		{names: constants.ReadmeFileNames, optional: true, idx: -1, name: &ins.ReadmeFileName, content: &ins.ReadmeContent},
+		{names: constants.LicenseFileNames, prefixes: constants.LicenseFilePrefixes, idx: -1, name: &ins.LicenseFileName, content: &ins.LicenseContent},
+		{names: constants.ChangelogFileNames, prefixes: constants.ChangelogFilePrefixes, optional: true, idx: -1, name: &ins.ChangelogFileName, content: &ins.ChangelogContent},
+		{names: []string{constants.GoModFileName}, idx: -1, name: &ins.GoModFileName, content: &ins.GoModContent},
+	}
+
+	imports := map[string]bool{}
+	var sourcesSize int64
+	tr := tar.NewReader(gzr)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the tar stream")
+		}
+
+		ins.Entries++
+		if ins.Entries > lim.MaxEntries {
+			ins.addInvalid(hdr.Name, fmt.Sprintf("The archive exceeds maximum allowed %d entries", lim.MaxEntries))
+			return ins, nil
+		}
+
+		// The PAX headers only hold the attributes of the archive or of the next entry,
+		// e.g. the commit id written by "git archive".
+		if hdr.Typeflag == tar.TypeXGlobalHeader || hdr.Typeflag == tar.TypeXHeader {
+			continue
+		}
+
+		if reason := checkEntry(hdr, lim); reason != "" {
+			ins.addInvalid(hdr.Name, reason)
+			continue
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		name := cleanPath(hdr.Name)
+		c, idx := captureOf(captures, name)
+		isSource := isGoSource(name)
+
+		// The size in the header is not trusted, the entry data is read to count the real size.
+		// Only the beginning of the captured and the source files is kept, enough to tell
+		// that a file is oversized, the rest is only hashed.
+		fileHash := sha256.New()
+		w := io.Writer(fileHash)
+		buff := &limitedBuffer{max: constants.ArchiveCapturedFileMaxSize + 1}
+		if c != nil || isSource {
+			w = io.MultiWriter(fileHash, buff)
+		}
+
+		n, err := io.CopyN(w, tr, lim.MaxUncompressedSize-ins.UncompressedSize+1)
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "Failed to read the tar stream")
+		}
+		ins.UncompressedSize += n
+
+		if ins.UncompressedSize > lim.MaxUncompressedSize {
+			ins.addInvalid(hdr.Name, fmt.Sprintf("The archive exceeds maximum allowed uncompressed size %d bytes", lim.MaxUncompressedSize))
+			return ins, nil
+		}
+
+		// The ratio of small archives is not meaningful, e.g. a few highly repetitive source files.
+		if ins.UncompressedSize > constants.ArchiveCompressionRatioMinSize && ins.UncompressedSize/cr.n > lim.MaxCompressionRatio {
+			ins.addInvalid(hdr.Name, fmt.Sprintf("The archive exceeds maximum allowed compression ratio %d", lim.MaxCompressionRatio))
+			return ins, nil
+		}
+
+		ins.Files = append(ins.Files, &File{
+			Path:   name,
+			Size:   n,
+			Mode:   hdr.Mode & 0777,
+			SHA256: hex.EncodeToString(fileHash.Sum(nil)),
+		})
+
+		if isSource {
+			f := ins.parseSource(name, buff.Bytes(), imports)
+			if f != nil {
+				sourcesSize += n
+				if sourcesSize > constants.ArchiveSourcesMaxSize {
+					ins.SourcesTruncated = true
+				} else {
+					dir := path.Dir(name)
+					ins.Sources[dir] = append(ins.Sources[dir], f)
+				}
+			}
+		}
+
+		if c != nil {
+			if n > constants.ArchiveCapturedFileMaxSize {
+				if c.optional || idx >= len(c.names) {
+					ins.SkippedFiles = append(ins.SkippedFiles, name)
+				} else {
+					ins.addInvalid(hdr.Name, fmt.Sprintf("File exceeds maximum allowed size %d bytes", constants.ArchiveCapturedFileMaxSize))
+				}
+				continue
+			}
+			c.idx = idx
+			*c.name = name
+			*c.content = buff.Bytes()
+		}
+	}
+
+	// Read the rest of the gzip stream and any trailing data, so that the digest covers the whole archive.
+	_, err = io.Copy(ioutil.Discard, gzr)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the gzip stream")
+		return nil, err
+	}
+
+	_, err = io.Copy(ioutil.Discard, cr)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the package data")
+		return nil, err
+	}
+
+	ins.Imports = make([]string, 0, len(imports))
+	for imp := range imports {
+		ins.Imports = append(ins.Imports, imp)
+	}
+	sort.Strings(ins.Imports)
+
+	ins.CompressedSize = cr.n
+	ins.SHA256 = hex.EncodeToString(hash256.Sum(nil))
+	ins.SHA512 = hex.EncodeToString(hash512.Sum(nil))
+
+	return ins, nil
+}
+
+// ReadFiles reads the regular files of a .tar.gz archive in a single pass, at most maxSize
+// bytes are read from each file. The files which are not found are missing in the result.
+func ReadFiles(data io.Reader, names map[string]bool, maxSize int64) (contents map[string][]byte, err error) {
+	gzr, err := gzip.NewReader(data)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the gzip stream")
+		return nil, err
+	}
+	defer gzr.Close()
+
+	contents = map[string][]byte{}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the tar stream")
+		}
+
+		name := cleanPath(hdr.Name)
+		if (hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA) || !names[name] {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(io.LimitReader(tr, maxSize))
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the tar stream")
+		}
+		contents[name] = content
+	}
+
+	return contents, nil
+}
+
+func (ins *Inspection) addInvalid(name, reason string) {
+	ins.Invalid = append(ins.Invalid, &types.ArchiveEntryError{
+		Path:   name,
+		Reason: reason,
+	})
+}
+
+// parseSource parses a Go source file along with its comments and adds its imports
+// to the imports set, it returns nil if the file could not be parsed.
+func (ins *Inspection) parseSource(name string, src []byte, imports map[string]bool) *ast.File {
+	if int64(len(src)) > constants.ArchiveCapturedFileMaxSize {
+		ins.UnparsedFiles = append(ins.UnparsedFiles, name)
+		return nil
+	}
+
+	f, err := parser.ParseFile(ins.FileSet, name, src, parser.ParseComments)
+	if err != nil {
+		ins.UnparsedFiles = append(ins.UnparsedFiles, name)
+		return nil
+	}
+
+	for _, imp := range f.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err == nil && importPath != "C" {
+			imports[importPath] = true
+		}
+	}
+
+	return f
+}
+
+// isGoSource checks whether the file is a Go source file which the go tool builds,
+// the files inside the testdata directories and the directories starting with '.'
+// or '_' are ignored just like the go tool does.
+func isGoSource(name string) bool {
+	if path.Ext(name) != ".go" {
+		return false
+	}
+
+	parts := strings.Split(name, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if dir == "testdata" || strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// captureOf returns the capture which the root level file belongs to, if the file
+// has a higher priority than the one already captured.
+func captureOf(captures []*captured, name string) (c *captured, idx int) {
+	for _, c := range captures {
+		idx := arr.FindStr(c.names, name)
+		if idx == -1 && hasPrefix(name, c.prefixes) {
+			idx = len(c.names)
+		}
+		if idx != -1 && (c.idx == -1 || idx < c.idx) {
+			return c, idx
+		}
+	}
+
+	return nil, -1
+}
+
+// hasPrefix checks whether a root level file name starts with one of the upper case prefixes.
+func hasPrefix(name string, prefixes []string) bool {
+	if strings.Contains(name, "/") {
+		return false
+	}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(strings.ToUpper(name), p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkEntry returns the reason why the entry is unsafe, or an empty string if it is safe.
+func checkEntry(hdr *tar.Header, lim *Limits) string {
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeRegA, tar.TypeDir:
+	case tar.TypeSymlink:
+		return "Symbolic links are not allowed"
+	case tar.TypeLink:
+		return "Hard links are not allowed"
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		return "Device and pipe entries are not allowed"
+	default:
+		return fmt.Sprintf("Unsupported entry type %q", hdr.Typeflag)
+	}
+
+	name := strings.Replace(hdr.Name, "\\", "/", -1)
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return "Absolute paths are not allowed"
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "Paths must not point outside of the archive"
+		}
+	}
+
+	cleaned := cleanPath(name)
+	if cleaned == "." {
+		return ""
+	}
+	if depth := strings.Count(cleaned, "/") + 1; depth > lim.MaxPathDepth {
+		return fmt.Sprintf("Path exceeds maximum allowed depth %d", lim.MaxPathDepth)
+	}
+
+	return ""
+}
+
+func cleanPath(name string) string {
+	return strings.Trim(path.Clean(name), "/")
+}
+
+// limitedBuffer keeps at most max bytes written to it and discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int64
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if room := lb.max - int64(lb.Len()); room > 0 {
+		if int64(len(p)) > room {
+			lb.Buffer.Write(p[:room])
+		} else {
+			lb.Buffer.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
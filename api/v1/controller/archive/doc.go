@@ -0,0 +1,4 @@
+/*
+Package archive provides controllers to inspect the uploaded package archives.
+*/
+package archive
@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// fileReaderBufferSize is the buffer size of a FileReader, enough for sniffing the content type.
+const fileReaderBufferSize = 512
+
+// FileReader streams the content of a file of a .tar.gz archive. It implements io.Seeker
+// for http.ServeContent, but only seeking forward is supported as the archive is a stream,
+// the seeks take effect on the next read.
+type FileReader struct {
+	gzr  *gzip.Reader
+	br   *bufio.Reader
+	size int64
+	pos  int64
+	off  int64
+}
+
+// OpenFile opens a regular file of a .tar.gz archive which is already inspected, at most
+// maxSize bytes are read. The last entry wins if the path occurs more than once, like
+// on extraction, so the headers of the archive are scanned before the file is opened.
+// Nil is returned if the file is not found.
+func OpenFile(data io.ReadSeeker, name string, maxSize int64) (fr *FileReader, err error) {
+	count, err := countEntries(data, name)
+	if err != nil || count == 0 {
+		return
+	}
+
+	_, err = data.Seek(0, io.SeekStart)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to rewind the archive")
+		return
+	}
+
+	gzr, err := gzip.NewReader(data)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the gzip stream")
+		return nil, err
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			gzr.Close()
+			if err == io.EOF {
+				return nil, errors.Errorf("File %s is missing in the archive", name)
+			}
+			return nil, errors.Wrap(err, "Failed to read the tar stream")
+		}
+
+		if !isEntryOf(hdr, name) {
+			continue
+		}
+
+		count--
+		if count > 0 {
+			continue
+		}
+
+		size := hdr.Size
+		if size > maxSize {
+			size = maxSize
+		}
+
+		fr = &FileReader{
+			gzr:  gzr,
+			br:   bufio.NewReaderSize(io.LimitReader(tr, size), fileReaderBufferSize),
+			size: size,
+		}
+		return fr, nil
+	}
+}
+
+// countEntries counts the regular file entries of a path in a .tar.gz archive.
+func countEntries(data io.Reader, name string) (count int, err error) {
+	gzr, err := gzip.NewReader(data)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read the gzip stream")
+		return
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "Failed to read the tar stream")
+		}
+
+		if isEntryOf(hdr, name) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func isEntryOf(hdr *tar.Header, name string) bool {
+	return (hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA) && cleanPath(hdr.Name) == name
+}
+
+// Size returns the number of bytes of the file content which can be read.
+func (fr *FileReader) Size() int64 {
+	return fr.size
+}
+
+// Peek returns the first bytes of the file content, at most 512 bytes, without consuming
+// them. It must be called before any read.
+func (fr *FileReader) Peek() ([]byte, error) {
+	head, err := fr.br.Peek(fileReaderBufferSize)
+	if err == io.EOF || err == bufio.ErrBufferFull {
+		err = nil
+	}
+
+	return head, err
+}
+
+// Read implements io.Reader.
+func (fr *FileReader) Read(p []byte) (n int, err error) {
+	if fr.off < fr.pos {
+		return 0, errors.New("Seeking backward in an archive file is not supported")
+	}
+
+	if fr.off > fr.pos {
+		skipped, err := io.CopyN(ioutil.Discard, fr.br, fr.off-fr.pos)
+		fr.pos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = fr.br.Read(p)
+	fr.pos += int64(n)
+	fr.off = fr.pos
+
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (fr *FileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += fr.off
+	case io.SeekEnd:
+		offset += fr.size
+	default:
+		return 0, errors.New("Invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("Negative position")
+	}
+	fr.off = offset
+
+	return offset, nil
+}
+
+// Close closes the gzip stream of the archive, the archive itself is not closed.
+func (fr *FileReader) Close() error {
+	return fr.gzr.Close()
+}
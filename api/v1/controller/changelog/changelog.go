@@ -0,0 +1,137 @@
+package changelog
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	setextRe  = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	versionRe = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)\b`)
+	dateRe    = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// ReleaseNotes holds the section of a version in a changelog, the date is empty
+// if the heading of the section has no date.
+type ReleaseNotes struct {
+	Version string
+	Date    string
+	Notes   string
+}
+
+// Parse splits a Markdown changelog into the sections of the versions. A section starts
+// at a heading containing a version e.g. "## [1.2.0] - 2019-05-01" or "v1.2.0", and ends
+// at the next one, the headings without versions e.g. "Unreleased" are kept in the section
+// before them. Only the headings at or above the level of the first version heading start
+// a section, so the deeper ones e.g. "### Go 1.13 support" are part of the notes.
+func Parse(content string) (sections []*ReleaseNotes) {
+	sections = []*ReleaseNotes{}
+	lines := strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n")
+
+	var (
+		current    *ReleaseNotes
+		body       []string
+		splitLevel int
+	)
+
+	flush := func() {
+		if current != nil {
+			current.Notes = strings.TrimSpace(strings.Join(body, "\n"))
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		heading, level, setext := headingOf(lines, i)
+
+		version := ""
+		if level > 0 && (splitLevel == 0 || level <= splitLevel) {
+			if m := versionRe.FindStringSubmatch(heading); m != nil {
+				version = m[1]
+			}
+		}
+
+		if version == "" {
+			if current != nil {
+				body = append(body, lines[i])
+			}
+			continue
+		}
+
+		flush()
+		if splitLevel == 0 {
+			splitLevel = level
+		}
+		current = &ReleaseNotes{
+			Version: version,
+			Date:    dateRe.FindString(heading),
+		}
+
+		// Skip the underline of a setext heading.
+		if setext {
+			i++
+		}
+	}
+	flush()
+
+	return sections
+}
+
+// headingOf returns the text and the level of the heading at the i-th line, the level
+// is zero if the line is not a heading. A setext heading takes the next line as well.
+func headingOf(lines []string, i int) (heading string, level int, setext bool) {
+	if m := headingRe.FindStringSubmatch(lines[i]); m != nil {
+		return m[2], len(m[1]), false
+	}
+
+	if i+1 < len(lines) && strings.TrimSpace(lines[i]) != "" {
+		if m := setextRe.FindStringSubmatch(lines[i+1]); m != nil {
+			level = 2
+			if m[1][0] == '=' {
+				level = 1
+			}
+			return strings.TrimSpace(lines[i]), level, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// Notes finds the section of a version in a changelog, or returns nil.
+func Notes(content, version string) *ReleaseNotes {
+	for _, s := range Parse(content) {
+		if ok, err := helper.IsSameVersion(s.Version, version); err == nil && ok {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// Excerpt returns the beginning of the release notes as a single line of text,
+// shortened at a word boundary.
+func Excerpt(notes string) string {
+	lines := strings.Split(notes, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimLeft(l, "-*+#> \t")
+	}
+
+	excerpt := strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	if utf8.RuneCountInString(excerpt) <= constants.ReleaseNotesExcerptMaxLength {
+		return excerpt
+	}
+
+	runes := []rune(excerpt)[:constants.ReleaseNotesExcerptMaxLength]
+	excerpt = string(runes)
+	if i := strings.LastIndex(excerpt, " "); i > 0 {
+		excerpt = excerpt[:i]
+	}
+
+	return excerpt + "…"
+}
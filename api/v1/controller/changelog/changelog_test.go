@@ -0,0 +1,132 @@
+package changelog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopx.io/gopx-api/api/v1/constants"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []*ReleaseNotes
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    []*ReleaseNotes{},
+		},
+		{
+			name:    "no versions",
+			content: "# Changelog\n\nNothing yet.\n",
+			want:    []*ReleaseNotes{},
+		},
+		{
+			name: "keep a changelog",
+			content: "# Changelog\n\n## [Unreleased]\n- Next\n\n" +
+				"## [1.2.0] - 2019-05-01\n### Added\n- Feature\n\n" +
+				"## [1.1.0] - 2019-04-01\n### Fixed\n- Bug\n",
+			want: []*ReleaseNotes{
+				{Version: "1.2.0", Date: "2019-05-01", Notes: "### Added\n- Feature"},
+				{Version: "1.1.0", Date: "2019-04-01", Notes: "### Fixed\n- Bug"},
+			},
+		},
+		{
+			name: "deeper version headings are notes",
+			content: "## v1.3.0\n- Faster builds\n\n### Go 1.13 support\n- Modules\n\n" +
+				"## v1.2.0\n- Initial\n",
+			want: []*ReleaseNotes{
+				{Version: "1.3.0", Notes: "- Faster builds\n\n### Go 1.13 support\n- Modules"},
+				{Version: "1.2.0", Notes: "- Initial"},
+			},
+		},
+		{
+			name:    "higher version headings split",
+			content: "### 2.0.0\n- Breaking\n\n## 1.0.0\n- First\n",
+			want: []*ReleaseNotes{
+				{Version: "2.0.0", Notes: "- Breaking"},
+				{Version: "1.0.0", Notes: "- First"},
+			},
+		},
+		{
+			name:    "setext headings",
+			content: "1.1.0 (2019-04-01)\n==================\n- Bug\n\nGo 1.12 support\n---------------\n- Modules\n\n1.0.0\n=====\n- First\n",
+			want: []*ReleaseNotes{
+				{Version: "1.1.0", Date: "2019-04-01", Notes: "- Bug\n\nGo 1.12 support\n---------------\n- Modules"},
+				{Version: "1.0.0", Notes: "- First"},
+			},
+		},
+		{
+			name:    "windows line endings",
+			content: "## 1.0.0-beta.1\r\n- First\r\n",
+			want: []*ReleaseNotes{
+				{Version: "1.0.0-beta.1", Notes: "- First"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %s, want %s", format(got), format(tt.want))
+			}
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	content := "## v1.1.0\n- Bug\n\n## v1.0.0\n- First\n"
+
+	if rn := Notes(content, "1.0.0"); rn == nil || rn.Notes != "- First" {
+		t.Errorf("Notes(1.0.0) = %s, want the notes of v1.0.0", format([]*ReleaseNotes{rn}))
+	}
+	if rn := Notes(content, "v1.1.0"); rn == nil || rn.Version != "1.1.0" {
+		t.Errorf("Notes(v1.1.0) = %s, want the notes of v1.1.0", format([]*ReleaseNotes{rn}))
+	}
+	if rn := Notes(content, "2.0.0"); rn != nil {
+		t.Errorf("Notes(2.0.0) = %s, want nil", format([]*ReleaseNotes{rn}))
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("word ", constants.ReleaseNotesExcerptMaxLength)
+	longWant := strings.TrimSpace(long[:constants.ReleaseNotesExcerptMaxLength]) + "…"
+
+	tests := []struct {
+		name  string
+		notes string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"markers", "### Added\n- Feature\n* Other\n+ Third\n> Quote", "Added Feature Other Third Quote"},
+		{"spaces", "  Fixed   the\tbug  \n\n", "Fixed the bug"},
+		{"short", "Short notes", "Short notes"},
+		{"long", long, longWant},
+		{"long word", strings.Repeat("é", constants.ReleaseNotesExcerptMaxLength+1), strings.Repeat("é", constants.ReleaseNotesExcerptMaxLength) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.notes); got != tt.want {
+				t.Errorf("Excerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func format(sections []*ReleaseNotes) string {
+	parts := []string{}
+	for _, s := range sections {
+		if s == nil {
+			parts = append(parts, "nil")
+			continue
+		}
+		parts = append(parts, "{"+s.Version+" "+s.Date+" "+strings.Replace(s.Notes, "\n", `\n`, -1)+"}")
+	}
+
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,5 @@
+/*
+Package changelog provides controllers to extract the release notes of the versions from
+the changelog files of the packages.
+*/
+package changelog
@@ -0,0 +1,4 @@
+/*
+Package docs provides controllers to render the documentation of the packages.
+*/
+package docs
@@ -0,0 +1,220 @@
+package docs
+
+import (
+	"fmt"
+	"go/doc/comment"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/types"
+)
+
+// policy sanitizes the HTML rendered from the doc comments, the comments are
+// written by the package authors so those are not trusted.
+var policy = bluemonday.UGCPolicy()
+
+var kindOrder = map[string]int{
+	constants.SymbolKindConst:  0,
+	constants.SymbolKindVar:    1,
+	constants.SymbolKindFunc:   2,
+	constants.SymbolKindType:   3,
+	constants.SymbolKindMethod: 3,
+}
+
+// SortSymbols sorts the symbols of a Go package like godoc does, the constants,
+// variables and functions come first, then every type followed by its methods.
+func SortSymbols(symbols []*types.PackageSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if kindOrder[a.Kind] != kindOrder[b.Kind] {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+
+		aType, bType := typeName(a), typeName(b)
+		if aType != bType {
+			return aType < bType
+		}
+
+		if (a.Kind == constants.SymbolKindMethod) != (b.Kind == constants.SymbolKindMethod) {
+			return b.Kind == constants.SymbolKindMethod
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+func typeName(s *types.PackageSymbol) string {
+	switch s.Kind {
+	case constants.SymbolKindType:
+		return s.Name
+	case constants.SymbolKindMethod:
+		return s.Receiver
+	default:
+		return ""
+	}
+}
+
+// Heading returns the heading of a symbol in the index, which is the signature
+// for the functions and methods.
+func Heading(s *types.PackageSymbol) string {
+	switch s.Kind {
+	case constants.SymbolKindFunc, constants.SymbolKindMethod:
+		return s.Signature
+	default:
+		return s.Kind + " " + s.Name
+	}
+}
+
+// anchor returns the HTML id of a symbol, the Go packages are numbered in the page
+// so that the ids are unique across the packages.
+func anchor(pkgIndex int, s *types.PackageSymbol) string {
+	if s.Kind == constants.SymbolKindMethod {
+		return fmt.Sprintf("pkg%d-%s.%s", pkgIndex, s.Receiver, s.Name)
+	}
+
+	return fmt.Sprintf("pkg%d-%s", pkgIndex, s.Name)
+}
+
+// exampleName returns the name which the examples of a symbol are associated with.
+func exampleName(s *types.PackageSymbol) string {
+	if s.Kind == constants.SymbolKindMethod {
+		return s.Receiver + "_" + s.Name
+	}
+
+	return s.Name
+}
+
+type symbolView struct {
+	Anchor    string
+	Heading   string
+	Signature string
+	Doc       template.HTML
+	Examples  []*exampleView
+}
+
+type exampleView struct {
+	Title  string
+	Doc    template.HTML
+	Code   string
+	Output string
+}
+
+type packageView struct {
+	ImportPath string
+	Name       string
+	Overview   template.HTML
+	Examples   []*exampleView
+	Symbols    []*symbolView
+}
+
+type pageView struct {
+	Name     string
+	Version  string
+	Packages []*packageView
+}
+
+// RenderHTML renders the documentation of a package version as an HTML page.
+// The doc comments are rendered like godoc does and sanitized.
+func RenderHTML(w io.Writer, pd *types.PackageDocs) (err error) {
+	page := &pageView{
+		Name:    pd.Name,
+		Version: pd.Version,
+	}
+
+	for i, p := range pd.Packages {
+		examples := map[string][]*exampleView{}
+		for _, e := range p.Examples {
+			title := "Example"
+			if e.Name != "" {
+				title += " " + e.Name
+			}
+			if e.Suffix != "" {
+				title += " (" + e.Suffix + ")"
+			}
+
+			examples[e.Name] = append(examples[e.Name], &exampleView{
+				Title:  title,
+				Doc:    docHTML(e.Doc),
+				Code:   e.Code,
+				Output: e.Output,
+			})
+		}
+
+		pv := &packageView{
+			ImportPath: p.ImportPath,
+			Name:       p.Name,
+			Overview:   docHTML(p.Overview),
+			Examples:   examples[""],
+		}
+
+		for _, s := range p.Symbols {
+			pv.Symbols = append(pv.Symbols, &symbolView{
+				Anchor:    anchor(i, s),
+				Heading:   Heading(s),
+				Signature: s.Signature,
+				Doc:       docHTML(s.Doc),
+				Examples:  examples[exampleName(s)],
+			})
+		}
+
+		page.Packages = append(page.Packages, pv)
+	}
+
+	err = pageTemplate.Execute(w, page)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to render the documentation page")
+		return
+	}
+
+	return nil
+}
+
+// docHTML renders a doc comment as sanitized HTML.
+func docHTML(text string) template.HTML {
+	var p comment.Parser
+	pr := &comment.Printer{HeadingLevel: 4}
+
+	return template.HTML(policy.SanitizeBytes(pr.HTML(p.Parse(text))))
+}
+
+var pageTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} {{.Version}} - GoPx</title>
+</head>
+<body>
+<h1>{{.Name}} <small>{{.Version}}</small></h1>
+{{range .Packages}}
+<section>
+<h2>package {{.Name}}</h2>
+<pre>import "{{.ImportPath}}"</pre>
+<h3>Overview</h3>
+{{.Overview}}
+{{range .Examples}}{{template "example" .}}{{end}}
+<h3>Index</h3>
+<ul>
+{{range .Symbols}}<li><a href="#{{.Anchor}}">{{.Heading}}</a></li>
+{{end}}</ul>
+{{range .Symbols}}
+<h3 id="{{.Anchor}}">{{.Heading}}</h3>
+<pre>{{.Signature}}</pre>
+{{.Doc}}
+{{range .Examples}}{{template "example" .}}{{end}}
+{{end}}
+</section>
+{{end}}
+</body>
+</html>
+{{define "example"}}<details>
+<summary>{{.Title}}</summary>
+{{.Doc}}
+<pre>{{.Code}}</pre>
+{{if .Output}}<p>Output:</p>
+<pre>{{.Output}}</pre>{{end}}
+</details>
+{{end}}`))
@@ -128,3 +128,9 @@ func SanitizeSortByCols(sortBy string, cols []string) []string {
 
 	return sCols
 }
+
+// EscapeLikeValue escapes the wildcards of a value matched with the LIKE operator,
+// so that it matches literally.
+func EscapeLikeValue(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
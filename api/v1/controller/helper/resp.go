@@ -56,3 +56,15 @@ func WriteResponseValue(w http.ResponseWriter, r *http.Request, data interface{}
 func WriteResponseValueOK(w http.ResponseWriter, r *http.Request, data interface{}) {
 	WriteResponseValue(w, r, data, http.StatusOK)
 }
+
+// WriteRedirect redirects the client to the input location with the specified status code.
+func WriteRedirect(w http.ResponseWriter, r *http.Request, location string, statusCode int) {
+	headers := w.Header()
+	setBasicHeaders(headers)
+
+	headers.Set("Location", location)
+	headers.Set("Content-Length", "0")
+	headers.Set("Status", fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)))
+
+	w.WriteHeader(statusCode)
+}
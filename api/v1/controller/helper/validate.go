@@ -9,6 +9,7 @@ import (
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
 	"gopx.io/gopx-api/api/v1/constants"
 )
 
@@ -76,3 +77,115 @@ func IsSameVersion(v1, v2 string) (ok bool, err error) {
 
 	return
 }
+
+// IsNewerVersion checks whether the semver version v1 is greater than v2, an empty v2
+// is older than any version.
+func IsNewerVersion(v1, v2 string) (ok bool, err error) {
+	oV1, err := semver.NewVersion(v1)
+	if err != nil {
+		return
+	}
+
+	if v2 == "" {
+		return true, nil
+	}
+
+	oV2, err := semver.NewVersion(v2)
+	if err != nil {
+		return
+	}
+
+	ok = oV1.GreaterThan(oV2)
+
+	return
+}
+
+// ValidateUsername checks whether the username meets the
+// naming constraints.
+func ValidateUsername(username string) error {
+	ln := utf8.RuneCountInString(username)
+	if !(ln > 0 && ln <= constants.UsernameMaxLength) {
+		return errors.Errorf("Username must be non-empty and maximum %d characters long", constants.UsernameMaxLength)
+	}
+
+	if matched, err := regexp.MatchString(`^[a-zA-Z0-9]+(?:-[a-zA-Z0-9]+)*$`, username); err != nil {
+		return constants.ErrInternalServer
+	} else if !matched {
+		return errors.Errorf("Username may only contain alphanumeric characters or single hyphens, and cannot begin or end with a hyphen")
+	}
+
+	return nil
+}
+
+// ValidatePackageCommand checks whether the name and the value of a command
+// of a package meet the constraints.
+func ValidatePackageCommand(name, command string) error {
+	ln := utf8.RuneCountInString(name)
+	if !(ln > 0 && ln <= constants.PackageCommandNameMaxLength) {
+		return errors.Errorf("Command name must be non-empty and maximum %d characters long", constants.PackageCommandNameMaxLength)
+	}
+
+	if matched, err := regexp.MatchString(`^[a-zA-Z0-9][a-zA-Z0-9._:-]*$`, name); err != nil {
+		return constants.ErrInternalServer
+	} else if !matched {
+		return errors.Errorf("Command name %s may only contain alphanumeric characters, '.', '_', ':' or '-', and must begin with an alphanumeric character", name)
+	}
+
+	ln = utf8.RuneCountInString(command)
+	if !(ln > 0 && ln <= constants.PackageCommandValueMaxLength) {
+		return errors.Errorf("Command %s must be non-empty and maximum %d characters long", name, constants.PackageCommandValueMaxLength)
+	}
+
+	return nil
+}
+
+// DependencyKind returns the kind of a dependency, the names with a '.' or '/'
+// are Go module paths since those are not allowed in GoPx package names.
+func DependencyKind(name string) string {
+	if strings.ContainsAny(name, "./") {
+		return constants.DependencyKindModule
+	}
+
+	return constants.DependencyKindGoPx
+}
+
+// ImportKind returns the kind of an import path of a Go source file, the paths
+// without a '.' in the first element belong to the standard library.
+func ImportKind(importPath string) string {
+	if strings.HasPrefix(importPath, constants.PackageImportPathPrefix) {
+		return constants.ImportKindGoPx
+	}
+
+	if elem := strings.SplitN(importPath, "/", 2)[0]; !strings.Contains(elem, ".") {
+		return constants.ImportKindStandard
+	}
+
+	return constants.ImportKindModule
+}
+
+// ImportedPackageName returns the GoPx package name of an import path, the import
+// path may point to a sub-package of the package.
+func ImportedPackageName(importPath string) string {
+	return strings.SplitN(strings.TrimPrefix(importPath, constants.PackageImportPathPrefix), "/", 2)[0]
+}
+
+// ValidatePackageDependency checks whether the name and the version constraint
+// of a dependency of a package are valid.
+func ValidatePackageDependency(name, constraint string) error {
+	if DependencyKind(name) == constants.DependencyKindModule {
+		if err := module.CheckPath(name); err != nil {
+			return errors.Errorf("Dependency %s is not a valid module path", name)
+		}
+	} else if err := ValidatePackageName(name); err != nil {
+		if err == constants.ErrInternalServer {
+			return err
+		}
+		return errors.Errorf("Dependency %s is not a valid package name", name)
+	}
+
+	if _, err := semver.NewConstraint(constraint); err != nil {
+		return errors.Errorf("Dependency %s has an invalid version constraint %s", name, constraint)
+	}
+
+	return nil
+}
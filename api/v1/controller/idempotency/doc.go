@@ -0,0 +1,5 @@
+/*
+Package idempotency provides controllers to store and replay the responses
+of requests carrying an Idempotency-Key header.
+*/
+package idempotency
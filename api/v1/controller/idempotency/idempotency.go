@@ -0,0 +1,205 @@
+package idempotency
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/log"
+)
+
+// QueryRow represents a single row to query an idempotency key data from database.
+type QueryRow struct {
+	Key         string
+	UserID      uint64
+	Method      string
+	Path        string
+	Fingerprint string
+	Status      string
+	StatusCode  int
+	Headers     http.Header
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// Begin reserves an idempotency key of an user for a request with the input fingerprint.
+// If the key is already reserved, it returns the existing record with created set to false,
+// so that the caller can replay or reject the request. An expired key, or a key of the same
+// request left processing by a crash, is taken over instead.
+func Begin(userID uint64, key, method, path, fingerprint string) (rec *QueryRow, created bool, err error) {
+	st := `
+	INSERT INTO idempotency_keys
+	(idempotency_key, user_id, method, path, fingerprint, status)
+	VALUES
+	(?, ?, ?, ?, ?, ?)
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, key, userID, method, path, fingerprint, constants.IdempotencyKeyStatusProcessing)
+	if err == nil {
+		return nil, true, nil
+	}
+
+	if !database.IsDuplicateEntry(err) {
+		err = errors.Wrap(err, "Failed to insert key to idempotency_keys table")
+		return
+	}
+
+	rec, err = query(userID, key)
+	if err != nil {
+		return
+	}
+
+	// The key is deleted in the meantime.
+	if rec == nil {
+		return Begin(userID, key, method, path, fingerprint)
+	}
+
+	// The key is expired but not cleaned yet, so it is reusable.
+	stale := time.Since(rec.CreatedAt) > constants.IdempotencyKeyTTL
+	if rec.Status == constants.IdempotencyKeyStatusProcessing && rec.Fingerprint == fingerprint {
+		stale = stale || time.Since(rec.CreatedAt) > constants.IdempotencyKeyProcessingTimeout
+	}
+
+	if stale {
+		created, err = takeOver(rec, method, path, fingerprint)
+		if err != nil || created {
+			return nil, created, err
+		}
+		return Begin(userID, key, method, path, fingerprint)
+	}
+
+	return rec, false, nil
+}
+
+// takeOver reserves a stale idempotency key again, it fails if another request has
+// taken it over in the meantime.
+func takeOver(rec *QueryRow, method, path, fingerprint string) (ok bool, err error) {
+	st := `
+	UPDATE idempotency_keys
+	SET method = ?, path = ?, fingerprint = ?, status = ?, status_code = NULL, headers = NULL, body = NULL, created_at = ?
+	WHERE user_id = ? and idempotency_key = ? and created_at = ?
+	`
+	dbConn := database.Conn()
+	r, err := dbConn.Exec(st, method, path, fingerprint, constants.IdempotencyKeyStatusProcessing, time.Now(), rec.UserID, rec.Key, rec.CreatedAt)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update idempotency_keys table")
+		return
+	}
+
+	n, err := r.RowsAffected()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update idempotency_keys table")
+		return
+	}
+
+	return n == 1, nil
+}
+
+// Complete stores the response of the request which reserved the idempotency key,
+// along with all the response headers set by the handler.
+func Complete(userID uint64, key string, statusCode int, headers http.Header, body []byte) (err error) {
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to marshal the response headers")
+		return
+	}
+
+	st := `
+	UPDATE idempotency_keys
+	SET status = ?, status_code = ?, headers = ?, body = ?
+	WHERE user_id = ? and idempotency_key = ?
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, constants.IdempotencyKeyStatusCompleted, statusCode, headersJSON, body, userID, key)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update idempotency_keys table")
+		return
+	}
+
+	return nil
+}
+
+// Abort releases an idempotency key so that the request can be retried with it.
+func Abort(userID uint64, key string) (err error) {
+	st := `
+	DELETE FROM idempotency_keys
+	WHERE user_id = ? and idempotency_key = ?
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, userID, key)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to delete key from idempotency_keys table")
+		return
+	}
+
+	return nil
+}
+
+// RunCleaner periodically removes the expired idempotency keys.
+// It never returns.
+func RunCleaner() {
+	st := `
+	DELETE FROM idempotency_keys
+	WHERE created_at < ?
+	`
+	dbConn := database.Conn()
+
+	for {
+		_, err := dbConn.Exec(st, time.Now().Add(-constants.IdempotencyKeyTTL))
+		if err != nil {
+			log.Error("Error %s", errors.Wrap(err, "Failed to delete expired keys from idempotency_keys table"))
+		}
+
+		time.Sleep(constants.IdempotencyKeyCleanInterval)
+	}
+}
+
+func query(userID uint64, key string) (rec *QueryRow, err error) {
+	sqlSt := `
+	SELECT idempotency_key, user_id, method, path, fingerprint, status, status_code, headers, body, created_at
+	FROM idempotency_keys
+	WHERE user_id = ? and idempotency_key = ?
+	`
+	var (
+		statusCode  sql.NullInt64
+		headersJSON []byte
+	)
+
+	rec = &QueryRow{}
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, userID, key).Scan(
+		&rec.Key,
+		&rec.UserID,
+		&rec.Method,
+		&rec.Path,
+		&rec.Fingerprint,
+		&rec.Status,
+		&statusCode,
+		&headersJSON,
+		&rec.Body,
+		&rec.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to read key from idempotency_keys table")
+		return nil, err
+	}
+
+	rec.StatusCode = int(statusCode.Int64)
+	rec.Headers = http.Header{}
+	if len(headersJSON) > 0 {
+		err = json.Unmarshal(headersJSON, &rec.Headers)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to decode the stored response headers")
+			return nil, err
+		}
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,4 @@
+/*
+Package job provides controllers to run package publishes asynchronously.
+*/
+package job
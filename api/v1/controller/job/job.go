@@ -0,0 +1,342 @@
+package job
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/pkg"
+	"gopx.io/gopx-api/api/v1/controller/user"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/config"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/log"
+)
+
+// ErrQueueFull indicates that no more publish jobs can be accepted at the moment.
+var ErrQueueFull = errors.New("Too many publish jobs are queued, try again later")
+
+// QueryRow represents a single row to query a publish job data from database.
+type QueryRow struct {
+	ID            string
+	UserID        uint64
+	Status        string
+	Stage         string
+	ErrorCode     int
+	ErrorMessage  string
+	Errors        []string
+	ErrorEntries  []*types.ArchiveEntryError
+	PackageName   string
+	Version       string
+	PublishStatus string
+	Warnings      []string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type task struct {
+	jobID     string
+	dataPath  string
+	ownerInfo *user.QueryRow
+	strict    bool
+}
+
+var queue = make(chan *task, constants.PublishJobQueueSize)
+
+// StartWorkers starts the bounded pool of workers which run the queued publish jobs.
+// The jobs which were left unfinished by a previous run of this instance are marked
+// as failed, since their uploaded data are not available anymore, unless their version
+// was already recorded, the outbox completes those. The jobs of the other instances
+// are left to them.
+func StartWorkers() {
+	st := `
+	UPDATE publish_jobs
+	INNER JOIN packages
+	ON packages.name = publish_jobs.package_name
+	INNER JOIN package_versions
+	ON package_versions.package_id = packages.id and package_versions.version = publish_jobs.version
+	SET publish_jobs.status = ?, publish_jobs.stage = ?, publish_jobs.publish_status = package_versions.status
+	WHERE publish_jobs.status IN (?, ?) and publish_jobs.instance_id = ?
+	`
+	dbConn := database.Conn()
+	_, err := dbConn.Exec(
+		st,
+		constants.PublishJobStatusSucceeded,
+		constants.PublishStageCompleted,
+		constants.PublishJobStatusQueued,
+		constants.PublishJobStatusRunning,
+		config.Service.InstanceID,
+	)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, "Failed to complete the interrupted publish jobs"))
+	}
+
+	st = `
+	UPDATE publish_jobs
+	SET status = ?, error_code = ?, error_message = ?
+	WHERE status IN (?, ?) and instance_id = ?
+	`
+	_, err = dbConn.Exec(
+		st,
+		constants.PublishJobStatusFailed,
+		500,
+		"The publish job was interrupted, please upload the package again",
+		constants.PublishJobStatusQueued,
+		constants.PublishJobStatusRunning,
+		config.Service.InstanceID,
+	)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, "Failed to fail the interrupted publish jobs"))
+	}
+
+	for i := 0; i < constants.PublishJobWorkers; i++ {
+		go worker()
+	}
+}
+
+// Submit creates a new publish job for the uploaded package data and queues it.
+// On success, the job takes the ownership of the file at dataPath and removes it
+// when done, otherwise the caller is responsible for removing it.
+func Submit(dataPath string, ownerInfo *user.QueryRow, strict bool) (job *QueryRow, err error) {
+	jobID, err := newJobID()
+	if err != nil {
+		return
+	}
+
+	st := `
+	INSERT INTO publish_jobs
+	(id, user_id, status, stage, instance_id)
+	VALUES
+	(?, ?, ?, ?, ?)
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, jobID, ownerInfo.ID, constants.PublishJobStatusQueued, constants.PublishStageQueued, config.Service.InstanceID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to insert job data to publish_jobs table")
+		return
+	}
+
+	select {
+	case queue <- &task{jobID: jobID, dataPath: dataPath, ownerInfo: ownerInfo, strict: strict}:
+	default:
+		finish(jobID, constants.PublishJobStatusFailed, &pkg.PublishError{StatusCode: 503, Message: ErrQueueFull.Error()}, nil)
+		err = ErrQueueFull
+		return
+	}
+
+	return Query(jobID)
+}
+
+// Query returns a single publish job, or nil if the job does not exist. The publish
+// status of a succeeded job is the current status of its version, as the outbox moves
+// the version forward after the job, and the version is failed if it was compensated.
+func Query(jobID string) (job *QueryRow, err error) {
+	sqlSt := `
+	SELECT id, user_id, status, stage, error_code, error_message, errors, error_entries, package_name, version, publish_status, warnings, created_at, updated_at
+	FROM publish_jobs
+	WHERE id = ?
+	`
+	var (
+		errorCode     sql.NullInt64
+		errorMessage  sql.NullString
+		errorList     []byte
+		errorEntries  []byte
+		packageName   sql.NullString
+		version       sql.NullString
+		publishStatus sql.NullString
+		warnings      []byte
+	)
+
+	job = &QueryRow{}
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, jobID).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&job.Stage,
+		&errorCode,
+		&errorMessage,
+		&errorList,
+		&errorEntries,
+		&packageName,
+		&version,
+		&publishStatus,
+		&warnings,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to read job data from publish_jobs table")
+		return nil, err
+	}
+
+	job.ErrorCode = int(errorCode.Int64)
+	job.ErrorMessage = errorMessage.String
+	if len(errorList) > 0 {
+		err = json.Unmarshal(errorList, &job.Errors)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to decode the errors of the job")
+			return nil, err
+		}
+	}
+	if len(errorEntries) > 0 {
+		err = json.Unmarshal(errorEntries, &job.ErrorEntries)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to decode the rejected archive entries of the job")
+			return nil, err
+		}
+	}
+	job.PackageName = packageName.String
+	job.Version = version.String
+	job.PublishStatus = publishStatus.String
+	if job.Status == constants.PublishJobStatusSucceeded && job.PackageName != "" {
+		sv, err := pkg.Version(job.PackageName, job.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		job.PublishStatus = constants.VersionStatusFailed
+		if sv != nil {
+			job.PublishStatus = sv.Status
+		}
+	}
+	if len(warnings) > 0 {
+		err = json.Unmarshal(warnings, &job.Warnings)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to decode the warnings of the job")
+			return nil, err
+		}
+	}
+
+	return job, nil
+}
+
+func worker() {
+	for t := range queue {
+		run(t)
+	}
+}
+
+func run(t *task) {
+	defer os.Remove(t.dataPath)
+
+	setStage(t.jobID, constants.PublishJobStatusRunning, constants.PublishStageValidating, nil)
+
+	data, err := os.Open(t.dataPath)
+	if err != nil {
+		log.Error("Error %s", err)
+		finish(t.jobID, constants.PublishJobStatusFailed, internalError(), nil)
+		return
+	}
+	defer data.Close()
+
+	result, err := pkg.Publish(data, t.ownerInfo, t.strict, func(stage string, meta *types.PackageMetaData) {
+		setStage(t.jobID, constants.PublishJobStatusRunning, stage, meta)
+	})
+	if err != nil {
+		if pErr, ok := err.(*pkg.PublishError); ok {
+			finish(t.jobID, constants.PublishJobStatusFailed, pErr, nil)
+			return
+		}
+
+		log.Error("Error %s", err)
+		finish(t.jobID, constants.PublishJobStatusFailed, internalError(), nil)
+		return
+	}
+
+	finish(t.jobID, constants.PublishJobStatusSucceeded, nil, result)
+}
+
+func internalError() *pkg.PublishError {
+	return &pkg.PublishError{StatusCode: 500, Message: constants.ErrInternalServer.Error()}
+}
+
+// setStage records the stage of a job, and the package version being published once it
+// is known, so that an interrupted job can be matched with its version, see StartWorkers.
+func setStage(jobID, status, stage string, meta *types.PackageMetaData) {
+	var packageName, version interface{}
+	if meta != nil {
+		packageName, version = meta.Name, meta.Version
+	}
+
+	st := `
+	UPDATE publish_jobs
+	SET status = ?, stage = ?, package_name = COALESCE(?, package_name), version = COALESCE(?, version)
+	WHERE id = ?
+	`
+	dbConn := database.Conn()
+	_, err := dbConn.Exec(st, status, stage, packageName, version, jobID)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, "Failed to update publish_jobs table"))
+	}
+}
+
+// finish records the outcome of a job, the error of a failed job or the result of a
+// succeeded one. All the errors and warnings of the publish are kept.
+func finish(jobID, status string, pErr *pkg.PublishError, result *pkg.PublishResult) {
+	var packageName, version, publishStatus, warnings interface{}
+	if result != nil {
+		packageName, version, publishStatus = result.Package.Name, result.Version, result.Status
+		warnings = marshalList(result.Warnings, "Failed to marshal the publish warnings")
+	}
+
+	var eCode, eMessage, eList, eEntries interface{}
+	if pErr != nil {
+		eCode, eMessage = pErr.StatusCode, pErr.Message
+		eList = marshalList(pErr.Errors, "Failed to marshal the publish errors")
+		warnings = marshalList(pErr.Warnings, "Failed to marshal the publish warnings")
+		if len(pErr.Entries) > 0 {
+			entriesJSON, err := json.Marshal(pErr.Entries)
+			if err != nil {
+				log.Error("Error %s", errors.Wrap(err, "Failed to marshal the rejected archive entries"))
+			} else {
+				eEntries = entriesJSON
+			}
+		}
+	}
+
+	st := `
+	UPDATE publish_jobs
+	SET status = ?, stage = ?, error_code = ?, error_message = ?, errors = ?, error_entries = ?, package_name = ?, version = ?, publish_status = ?, warnings = ?
+	WHERE id = ?
+	`
+	dbConn := database.Conn()
+	_, err := dbConn.Exec(st, status, constants.PublishStageCompleted, eCode, eMessage, eList, eEntries, packageName, version, publishStatus, warnings, jobID)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, "Failed to update publish_jobs table"))
+	}
+}
+
+// marshalList encodes a list of messages for the database, an empty list is stored as NULL.
+func marshalList(list []string, errMessage string) interface{} {
+	if len(list) == 0 {
+		return nil
+	}
+
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, errMessage))
+		return nil
+	}
+
+	return listJSON
+}
+
+func newJobID() (id string, err error) {
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to generate job id")
+		return
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,201 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/arr"
+)
+
+// APIDiff holds the differences between the exported APIs of two versions of a package.
+// Change is the minimum version bump the differences require.
+type APIDiff struct {
+	Change  string
+	Added   []*Symbol
+	Removed []*Symbol
+	Changed []*SymbolChange
+}
+
+// SymbolChange holds an exported symbol whose kind or signature is changed.
+type SymbolChange struct {
+	Old *Symbol
+	New *Symbol
+}
+
+// symbolKey identifies a symbol across the versions of a package.
+func symbolKey(s *Symbol) string {
+	return fmt.Sprintf("%s|%s|%s", s.ImportPath, s.Receiver, s.Name)
+}
+
+// DiffSymbols compares the exported symbols of two versions. The removed and changed
+// symbols are breaking changes which require a major version, the added symbols
+// require a minor version, and a patch version is enough otherwise.
+// Every change of a signature is considered as breaking, even if it is compatible e.g.
+// a new field of a struct.
+func DiffSymbols(oldSymbols, newSymbols []*Symbol) (diff *APIDiff) {
+	diff = &APIDiff{
+		Change:  constants.APIChangePatch,
+		Added:   []*Symbol{},
+		Removed: []*Symbol{},
+		Changed: []*SymbolChange{},
+	}
+
+	oldMap := make(map[string]*Symbol, len(oldSymbols))
+	for _, s := range oldSymbols {
+		oldMap[symbolKey(s)] = s
+	}
+
+	newMap := make(map[string]*Symbol, len(newSymbols))
+	for _, s := range newSymbols {
+		newMap[symbolKey(s)] = s
+
+		old, ok := oldMap[symbolKey(s)]
+		if !ok {
+			diff.Added = append(diff.Added, s)
+			continue
+		}
+
+		if old.Kind != s.Kind || old.Signature != s.Signature {
+			diff.Changed = append(diff.Changed, &SymbolChange{Old: old, New: s})
+		}
+	}
+
+	for _, s := range oldSymbols {
+		if _, ok := newMap[symbolKey(s)]; !ok {
+			diff.Removed = append(diff.Removed, s)
+		}
+	}
+
+	switch {
+	case len(diff.Removed) > 0 || len(diff.Changed) > 0:
+		diff.Change = constants.APIChangeMajor
+	case len(diff.Added) > 0:
+		diff.Change = constants.APIChangeMinor
+	}
+
+	return diff
+}
+
+// CompareVersions compares the exported APIs of two versions of a package.
+func CompareVersions(packageID uint64, fromVersion, toVersion string) (diff *APIDiff, err error) {
+	fromSymbols, err := Symbols(packageID, fromVersion)
+	if err != nil {
+		return
+	}
+
+	toSymbols, err := Symbols(packageID, toVersion)
+	if err != nil {
+		return
+	}
+
+	return DiffSymbols(fromSymbols, toSymbols), nil
+}
+
+// versionBump returns the part of the version which is incremented from the old version.
+func versionBump(oldVersion, newVersion *semver.Version) string {
+	switch {
+	case newVersion.Major() > oldVersion.Major():
+		return constants.APIChangeMajor
+	case newVersion.Major() == oldVersion.Major() && newVersion.Minor() > oldVersion.Minor():
+		return constants.APIChangeMinor
+	default:
+		return constants.APIChangePatch
+	}
+}
+
+// apiComplete checks whether the exported API of an archive can be extracted completely,
+// i.e. all of its Go source files are parsed.
+func apiComplete(ins *archive.Inspection) bool {
+	return !ins.SourcesTruncated && len(ins.UnparsedFiles) == 0
+}
+
+// apiExtracted checks whether the complete exported API of a package version is recorded.
+func apiExtracted(packageID uint64, version string) (ok bool, err error) {
+	sqlSt := `
+	SELECT api_extracted
+	FROM package_versions
+	WHERE package_id = ? and version = ?
+	`
+	var extracted sql.NullBool
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&extracted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+
+	return extracted.Bool, nil
+}
+
+// checkSemver compares the exported API of the upload against the previous release of
+// the package, and warns or fails according to the semver policy of the package if the
+// version bump is smaller than the API changes require.
+// The versions with the major version zero and the pre-release versions are not checked,
+// anything may change in those. The check is skipped with a warning if the Go sources of
+// the upload are not parsed completely.
+func (v *Validation) checkSemver() (err error) {
+	if v.Package == nil || v.Package.SemverPolicy == constants.SemverPolicyOff {
+		return nil
+	}
+
+	newVersion, err := semver.NewVersion(v.Meta.Version)
+	if err != nil || newVersion.Major() == 0 || newVersion.Prerelease() != "" {
+		return nil
+	}
+
+	// The symbols of the files which are not parsed would look removed.
+	if !apiComplete(v.Inspection) {
+		v.warn("The API changes are not checked against the semver policy, since the Go sources are not parsed completely")
+		return nil
+	}
+
+	prev, err := ResolveVersion(v.Package.Name, "<"+newVersion.String())
+	if err != nil || prev == "" {
+		return
+	}
+
+	// The previous release is published before the API extraction, or its API is incomplete.
+	extracted, err := apiExtracted(v.Package.ID, prev)
+	if err != nil || !extracted {
+		return
+	}
+
+	oldSymbols, err := Symbols(v.Package.ID, prev)
+	if err != nil {
+		return
+	}
+
+	newSymbols := extractSymbols(v.Inspection.FileSet, sourcePackages(v.Inspection, modulePath(v.Meta, v.GoMod)))
+	diff := DiffSymbols(oldSymbols, newSymbols)
+
+	oldVersion, err := semver.NewVersion(prev)
+	if err != nil {
+		return nil
+	}
+
+	bump := versionBump(oldVersion, newVersion)
+	if arr.FindStr(constants.APIChanges, bump) >= arr.FindStr(constants.APIChanges, diff.Change) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("The API changes since version %s require a %s version, but %s is a %s version (%d added, %d removed, %d changed)",
+		prev, diff.Change, v.Meta.Version, bump, len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+	if v.Package.SemverPolicy == constants.SemverPolicyReject {
+		v.fail(http.StatusBadRequest, msg)
+	} else {
+		v.warn(msg)
+	}
+
+	return nil
+}
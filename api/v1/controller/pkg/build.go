@@ -0,0 +1,408 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	gotypes "go/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-api/pkg/controller/storage"
+	"gopx.io/gopx-common/str"
+)
+
+// BuildResult holds the outcome of type-checking the Go sources of a package version.
+type BuildResult struct {
+	Status      string
+	Diagnostics []string
+}
+
+func (br *BuildResult) addDiagnostic(msg string) {
+	if len(br.Diagnostics) < constants.BuildDiagnosticsMaxCount {
+		br.Diagnostics = append(br.Diagnostics, msg)
+	}
+}
+
+// sourceModule holds the parsed Go sources of a module.
+type sourceModule struct {
+	path string
+	ins  *archive.Inspection
+}
+
+// stdImporter imports the standard library from the sources of the local GOROOT. It is
+// shared by all the type checks, so every standard package is type-checked only once per
+// process, and guarded as the source importer is not safe for concurrent use.
+var stdImporter = struct {
+	sync.Mutex
+	imp gotypes.Importer
+}{imp: importer.ForCompiler(token.NewFileSet(), "source", nil)}
+
+func importStd(importPath string) (*gotypes.Package, error) {
+	stdImporter.Lock()
+	defer stdImporter.Unlock()
+
+	return stdImporter.imp.Import(importPath)
+}
+
+// sourceImporter resolves the imports while type-checking, the standard library
+// from the sources of the local GOROOT, and the packages of the module and its GoPx
+// dependencies from their parsed sources. The GoPx dependencies are loaded from the
+// stored archives of their published versions. The imports whose module can not be
+// resolved are recorded in unresolved, the check is not complete without them.
+// Every package is checked once, the type errors of the packages of the checked
+// module are passed to report, the dependencies are used as they are.
+type sourceImporter struct {
+	modules    []*sourceModule
+	deps       map[string]string
+	pkgs       map[string]*gotypes.Package
+	loading    map[string]bool
+	unresolved []string
+	report     func(err error)
+}
+
+func newSourceImporter(mod *sourceModule, deps map[string]string) *sourceImporter {
+	return &sourceImporter{
+		modules: []*sourceModule{mod},
+		deps:    deps,
+		pkgs:    map[string]*gotypes.Package{},
+		loading: map[string]bool{},
+	}
+}
+
+// Import implements types.Importer of the go/types package.
+func (si *sourceImporter) Import(importPath string) (*gotypes.Package, error) {
+	if p, ok := si.pkgs[importPath]; ok {
+		return p, nil
+	}
+
+	if helper.ImportKind(importPath) == constants.ImportKindStandard {
+		return importStd(importPath)
+	}
+
+	mod, err := si.module(importPath)
+	if err != nil {
+		si.unresolve(fmt.Sprintf("%s: %s", importPath, err))
+		return nil, err
+	}
+
+	if si.loading[importPath] {
+		return nil, errors.Errorf("import cycle through %s", importPath)
+	}
+	si.loading[importPath] = true
+	defer delete(si.loading, importPath)
+
+	onError := func(err error) {}
+	if mod == si.modules[0] && si.report != nil {
+		onError = si.report
+	}
+
+	p, err := si.check(mod, importPath, onError)
+	if err != nil {
+		return nil, err
+	}
+	si.pkgs[importPath] = p
+
+	return p, nil
+}
+
+func (si *sourceImporter) unresolve(msg string) {
+	for _, m := range si.unresolved {
+		if m == msg {
+			return
+		}
+	}
+	si.unresolved = append(si.unresolved, msg)
+}
+
+// module returns the module which the import path belongs to, the GoPx dependencies
+// are loaded on the first use.
+func (si *sourceImporter) module(importPath string) (mod *sourceModule, err error) {
+	for _, m := range si.modules {
+		if importPath == m.path || strings.HasPrefix(importPath, m.path+"/") {
+			return m, nil
+		}
+	}
+
+	if helper.ImportKind(importPath) != constants.ImportKindGoPx {
+		return nil, errors.Errorf("module of %s can not be resolved offline", importPath)
+	}
+
+	name := helper.ImportedPackageName(importPath)
+	constraint := si.deps[name]
+	if str.IsEmpty(constraint) {
+		constraint = "*"
+	}
+
+	version, err := ResolveVersion(name, constraint)
+	if err != nil {
+		return
+	}
+
+	if str.IsEmpty(version) {
+		return nil, errors.Errorf("no published version of %s satisfies %s", name, constraint)
+	}
+
+	f, err := storage.OpenArchive(name, version)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	ins, err := archive.Inspect(f, archive.ConfiguredLimits())
+	if err != nil {
+		return
+	}
+
+	mod = &sourceModule{path: constants.PackageImportPathPrefix + name, ins: ins}
+	si.modules = append(si.modules, mod)
+
+	return mod, nil
+}
+
+// check type-checks the package of the import path, the type errors are passed to onError.
+func (si *sourceImporter) check(mod *sourceModule, importPath string, onError func(err error)) (*gotypes.Package, error) {
+	dir := strings.TrimPrefix(strings.TrimPrefix(importPath, mod.path), "/")
+	if dir == "" {
+		dir = "."
+	}
+
+	files := buildFiles(mod.ins, dir)
+	if len(files) == 0 {
+		return nil, errors.Errorf("no buildable Go source files in %s", importPath)
+	}
+
+	conf := &gotypes.Config{
+		Importer:    si,
+		FakeImportC: true,
+		Error:       onError,
+	}
+
+	// The package is returned even if it has type errors.
+	p, _ := conf.Check(importPath, mod.ins.FileSet, files, nil)
+
+	return p, nil
+}
+
+// buildFiles returns the non-test source files of a directory which match the build
+// constraints for the local platform.
+func buildFiles(ins *archive.Inspection, dir string) (files []*ast.File) {
+	for _, f := range ins.Sources[dir] {
+		if isTestFile(ins, f) {
+			continue
+		}
+
+		if matchFile(ins, build.Default, dir, f) {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// isTestFile checks whether a parsed source file is a test file.
+func isTestFile(ins *archive.Inspection, f *ast.File) bool {
+	return strings.HasSuffix(ins.FileSet.File(f.Pos()).Name(), "_test.go")
+}
+
+// matchFile checks whether a parsed source file matches the build constraints, both the
+// file name and the build tags, for the platform of the build context.
+func matchFile(ins *archive.Inspection, ctx build.Context, dir string, f *ast.File) bool {
+	ctx.OpenFile = func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(fileHeader(f))), nil
+	}
+
+	ok, err := ctx.MatchFile(dir, path.Base(ins.FileSet.File(f.Pos()).Name()))
+	return err == nil && ok
+}
+
+// fileHeader recreates the header of a source file from its AST, which is enough for
+// evaluating the build constraints of the file.
+func fileHeader(f *ast.File) string {
+	var sb strings.Builder
+	for _, cg := range f.Comments {
+		if cg.End() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			sb.WriteString(c.Text)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("package ")
+	sb.WriteString(f.Name.Name)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// checkBuild type-checks the Go sources of the upload offline, and warns or fails
+// according to the build policy of the package if the sources do not compile.
+// The check is skipped when it could not be complete e.g. the sources import
+// external modules, which are not available offline.
+func (v *Validation) checkBuild() (err error) {
+	policy := constants.DefaultBuildPolicy
+	if v.Package != nil {
+		policy = v.Package.BuildPolicy
+	}
+
+	if policy == constants.BuildPolicyOff {
+		return nil
+	}
+
+	v.Build = typeCheck(v.Inspection, v.Meta, v.GoMod)
+	if v.Build.Status != constants.BuildStatusFailed {
+		return nil
+	}
+
+	msg := fmt.Sprintf("The Go sources do not compile (%d problems)", len(v.Build.Diagnostics))
+	if len(v.Build.Diagnostics) > 0 {
+		msg = fmt.Sprintf("%s, the first one is: %s", msg, v.Build.Diagnostics[0])
+	}
+
+	if policy == constants.BuildPolicyReject {
+		v.fail(http.StatusBadRequest, msg)
+	} else {
+		v.warn(msg)
+	}
+
+	return nil
+}
+
+// typeCheck type-checks every package of the Go sources of a package version.
+func typeCheck(ins *archive.Inspection, meta *types.PackageMetaData, gm *GoModule) (br *BuildResult) {
+	br = &BuildResult{Status: constants.BuildStatusSkipped, Diagnostics: []string{}}
+
+	if len(ins.Sources) == 0 && len(ins.UnparsedFiles) == 0 {
+		br.addDiagnostic("No Go source files found")
+		return br
+	}
+
+	if ins.SourcesTruncated {
+		br.addDiagnostic(fmt.Sprintf("The Go sources exceed %d bytes", constants.ArchiveSourcesMaxSize))
+		return br
+	}
+
+	if _, err := os.Stat(build.Default.GOROOT); str.IsEmpty(build.Default.GOROOT) || err != nil {
+		br.addDiagnostic("The standard library is not available")
+		return br
+	}
+
+	modPath := modulePath(meta, gm)
+	external := []string{}
+	for _, importPath := range ins.Imports {
+		if helper.ImportKind(importPath) == constants.ImportKindModule && importPath != modPath && !strings.HasPrefix(importPath, modPath+"/") {
+			external = append(external, importPath)
+		}
+	}
+
+	if len(external) > 0 {
+		br.addDiagnostic(fmt.Sprintf("Imports of external modules can not be resolved offline: %s", strings.Join(external, ", ")))
+		return br
+	}
+
+	br.Status = constants.BuildStatusPassed
+
+	for _, name := range ins.UnparsedFiles {
+		br.Status = constants.BuildStatusFailed
+		br.addDiagnostic(fmt.Sprintf("%s: could not be parsed", name))
+	}
+
+	dirs := make([]string, 0, len(ins.Sources))
+	for dir := range ins.Sources {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	si := newSourceImporter(&sourceModule{path: modPath, ins: ins}, meta.Dependencies)
+	si.report = func(err error) {
+		br.Status = constants.BuildStatusFailed
+		br.addDiagnostic(err.Error())
+	}
+
+	for _, dir := range dirs {
+		if len(buildFiles(ins, dir)) == 0 {
+			continue
+		}
+
+		// The packages imported by the ones checked before are already checked, Import
+		// returns those as they are.
+		_, err := si.Import(path.Join(modPath, dir))
+		if err != nil {
+			si.report(err)
+		}
+	}
+
+	// The type errors caused by the missing dependencies are not the fault of the sources.
+	if len(si.unresolved) > 0 {
+		br = &BuildResult{Status: constants.BuildStatusSkipped, Diagnostics: []string{}}
+		br.addDiagnostic(fmt.Sprintf("Imports of GoPx dependencies can not be resolved: %s", strings.Join(si.unresolved, ", ")))
+	}
+
+	return br
+}
+
+// buildColumns returns the build status and the encoded diagnostics of a pending version,
+// those are NULL for the versions which are not checked.
+func buildColumns(br *BuildResult) (status sql.NullString, diagnosticsJSON []byte, err error) {
+	if br == nil {
+		return
+	}
+
+	diagnosticsJSON, err = json.Marshal(br.Diagnostics)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to encode build diagnostics")
+		return
+	}
+
+	return sql.NullString{String: br.Status, Valid: true}, diagnosticsJSON, nil
+}
+
+// BuildDiagnostics returns the diagnostics of the type-check of a package version.
+func BuildDiagnostics(packageID uint64, version string) (diagnostics []string, err error) {
+	sqlSt := `
+	SELECT build_diagnostics
+	FROM package_versions
+	WHERE package_id = ? and version = ?
+	`
+	dbConn := database.Conn()
+
+	var diagnosticsJSON []byte
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&diagnosticsJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+
+	if len(diagnosticsJSON) == 0 {
+		return nil, nil
+	}
+
+	err = json.Unmarshal(diagnosticsJSON, &diagnostics)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to decode build diagnostics")
+		return nil, err
+	}
+
+	return diagnostics, nil
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/controller/changelog"
+	"gopx.io/gopx-api/api/v1/controller/spdx"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/str"
+)
+
+// Changelog holds the changelog file of a package version along with the release
+// notes of the version, the notes are nil if the changelog has no section for it.
+type Changelog struct {
+	FileName string
+	Content  string
+	Notes    *changelog.ReleaseNotes
+}
+
+// insertChangelog inserts the changelog and the license files of a pending version, the
+// release notes of the version are extracted from the changelog and the license is
+// detected from the license file.
+func insertChangelog(tx *sql.Tx, packageID uint64, version string, ins *archive.Inspection) (err error) {
+	if !str.IsEmpty(ins.LicenseFileName) {
+		st := `
+		INSERT INTO package_license
+		(package_id, version, name, content, detected)
+		VALUES
+		(?, ?, ?, ?, ?)
+		`
+		detected := spdx.Detect(ins.LicenseContent)
+		_, err = tx.Exec(st, packageID, version, ins.LicenseFileName, ins.LicenseContent, sql.NullString{String: detected, Valid: detected != ""})
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert license to package_license table")
+			return
+		}
+	}
+
+	if str.IsEmpty(ins.ChangelogFileName) {
+		return nil
+	}
+
+	var (
+		notes, notesDate, excerpt sql.NullString
+	)
+	if rn := changelog.Notes(string(ins.ChangelogContent), version); rn != nil {
+		notes = sql.NullString{String: rn.Notes, Valid: true}
+		notesDate = sql.NullString{String: rn.Date, Valid: rn.Date != ""}
+		excerpt = sql.NullString{String: changelog.Excerpt(rn.Notes), Valid: true}
+	}
+
+	st := `
+	INSERT INTO package_changelog
+	(package_id, version, name, content, notes, notes_date, excerpt)
+	VALUES
+	(?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(st, packageID, version, ins.ChangelogFileName, ins.ChangelogContent, notes, notesDate, excerpt)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to insert changelog to package_changelog table")
+		return
+	}
+
+	return nil
+}
+
+// VersionChangelog returns the changelog of a package version, or nil if the version
+// has no changelog file.
+func VersionChangelog(packageID uint64, version string) (cl *Changelog, err error) {
+	sqlSt := `
+	SELECT name, content, notes, notes_date
+	FROM package_changelog
+	WHERE package_id = ? and version = ?
+	`
+	var (
+		content          []byte
+		notes, notesDate sql.NullString
+	)
+
+	cl = &Changelog{}
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&cl.FileName, &content, &notes, &notesDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+
+	cl.Content = string(content)
+	if notes.Valid {
+		cl.Notes = &changelog.ReleaseNotes{
+			Version: version,
+			Date:    notesDate.String,
+			Notes:   notes.String,
+		}
+	}
+
+	return cl, nil
+}
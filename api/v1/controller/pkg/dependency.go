@@ -0,0 +1,239 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/arr"
+	"gopx.io/gopx-common/str"
+)
+
+// Dependency holds a single declared dependency of a package version.
+type Dependency struct {
+	Name       string
+	Constraint string
+	Kind       string
+}
+
+// ResolvedDependency holds a GoPx package version in a resolved dependency graph.
+type ResolvedDependency struct {
+	Name         string
+	Version      string
+	Dependencies []*Dependency
+}
+
+// UnresolvedDependency holds a GoPx dependency which no published version satisfies.
+type UnresolvedDependency struct {
+	Name       string
+	Constraint string
+	RequiredBy string
+}
+
+// DependencyGraph holds the resolved transitive dependencies of a package version.
+type DependencyGraph struct {
+	Resolved   []*ResolvedDependency
+	Unresolved []*UnresolvedDependency
+	Truncated  bool
+}
+
+// Dependencies returns the declared dependencies of a package version.
+func Dependencies(packageID uint64, version string) (deps []*Dependency, err error) {
+	sqlSt := `
+	SELECT name, version_constraint, kind
+	FROM package_dependencies
+	WHERE package_id = ? and version = ?
+	ORDER BY name ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	deps = []*Dependency{}
+	for rows.Next() {
+		dep := &Dependency{}
+		err = rows.Scan(&dep.Name, &dep.Constraint, &dep.Kind)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package dependencies query result")
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package dependencies query result")
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+// ResolveVersion returns the highest published version of a package which satisfies
+// the constraint, or an empty string if there is none.
+func ResolveVersion(packageName, constraint string) (version string, err error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to parse version constraint %s", constraint)
+		return
+	}
+
+	vh, err := Versions(packageName)
+	if err != nil || vh == nil {
+		return
+	}
+
+	var best *semver.Version
+	for _, v := range *vh.Versions {
+		if v.Status != constants.VersionStatusCommitted {
+			continue
+		}
+
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil || !c.Check(sv) {
+			continue
+		}
+
+		if best == nil || sv.GreaterThan(best) {
+			best, version = sv, v.Version
+		}
+	}
+
+	return version, nil
+}
+
+// ResolveDependencies resolves the transitive GoPx dependencies of a package version,
+// every dependency is resolved to its highest published version which satisfies the
+// constraint. The module dependencies are not resolved, they are listed as declared.
+func ResolveDependencies(packageName, version string, deps []*Dependency) (graph *DependencyGraph, err error) {
+	graph = &DependencyGraph{}
+
+	type node struct {
+		name    string
+		version string
+		deps    []*Dependency
+	}
+
+	visited := map[string]bool{}
+	queue := []*node{{name: packageName, version: version, deps: deps}}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for _, dep := range n.deps {
+			if dep.Kind != constants.DependencyKindGoPx {
+				continue
+			}
+
+			version, err := ResolveVersion(dep.Name, dep.Constraint)
+			if err != nil {
+				return nil, err
+			}
+
+			if version == "" {
+				graph.Unresolved = append(graph.Unresolved, &UnresolvedDependency{
+					Name:       dep.Name,
+					Constraint: dep.Constraint,
+					RequiredBy: nodeKey(n.name, n.version),
+				})
+				continue
+			}
+
+			key := nodeKey(dep.Name, version)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			if len(graph.Resolved) >= constants.DependencyGraphMaxSize {
+				graph.Truncated = true
+				return graph, nil
+			}
+
+			sv, err := Version(dep.Name, version)
+			if err != nil {
+				return nil, err
+			}
+			if sv == nil {
+				continue
+			}
+
+			depDeps, err := Dependencies(sv.PackageID, version)
+			if err != nil {
+				return nil, err
+			}
+
+			graph.Resolved = append(graph.Resolved, &ResolvedDependency{
+				Name:         dep.Name,
+				Version:      version,
+				Dependencies: depDeps,
+			})
+			queue = append(queue, &node{name: dep.Name, version: version, deps: depDeps})
+		}
+	}
+
+	return graph, nil
+}
+
+func nodeKey(name, version string) string {
+	return fmt.Sprintf("%s@%s", name, version)
+}
+
+// dependentsCountSt counts the distinct packages whose latest version depends on the package,
+// it is correlated with the packages table of the enclosing query.
+const dependentsCountSt = `SELECT COUNT(DISTINCT dependents.package_id) FROM package_dependencies AS dependents
+	INNER JOIN packages AS dependent_packages ON dependent_packages.id = dependents.package_id AND dependent_packages.latest_version = dependents.version
+	WHERE dependents.name = packages.name AND dependents.kind = '` + constants.DependencyKindGoPx + `'`
+
+// Dependents queries the packages whose latest version depends on the input package.
+// The result can be sorted by downloads or name.
+func Dependents(packageName string, pc *helper.PaginationConfig, sc *helper.SortingConfig) (pkgs []*QueryRow, err error) {
+	if pc == nil {
+		pc = &helper.PaginationConfig{}
+	}
+	if sc == nil {
+		sc = &helper.SortingConfig{}
+	}
+
+	sanSortByCols := helper.SanitizeSortByCols(sc.SortBy, constants.PackageDependentsSortByCols)
+	if len(sanSortByCols) == 0 {
+		sanSortByCols = []string{constants.PackageDependentsDefaultSortByCol}
+	}
+	for i, v := range sanSortByCols {
+		sanSortByCols[i] = constants.PackageDependentsSortByDbColsMap[arr.FindStr(constants.PackageDependentsSortByCols, v)]
+	}
+
+	sc.Order = strings.ToUpper(sc.Order)
+	if str.IsEmpty(sc.Order) || arr.FindStr(constants.SortOrders, sc.Order) == -1 {
+		sc.Order = constants.DefaultSortOrder
+	}
+
+	if pc.Page <= 0 {
+		pc.Page = 1
+	}
+	if pc.PerPageCount <= 0 || pc.PerPageCount > uint64(constants.PackagesQueryMaxPageSize) {
+		pc.PerPageCount = uint64(constants.PackagesQueryMaxPageSize)
+	}
+	qLimit, qOffset := pc.PerPageCount, (pc.Page-1)*pc.PerPageCount
+
+	whereClauseSt := "EXISTS (SELECT 1 FROM package_dependencies WHERE package_dependencies.package_id = packages.id and package_dependencies.version = packages.latest_version and package_dependencies.name = ? and package_dependencies.kind = ?)"
+	sortBySt := fmt.Sprintf("%s %s", strings.Join(sanSortByCols, ","), sc.Order)
+	limitSt := fmt.Sprintf("%d", qLimit)
+	offsetSt := fmt.Sprintf("%d", qOffset)
+
+	pkgs, err = Query(whereClauseSt, sortBySt, limitSt, offsetSt, packageName, constants.DependencyKindGoPx)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to query dependents data from database")
+		return nil, err
+	}
+
+	return pkgs, nil
+}
@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"database/sql"
+	"go/doc"
+	"go/token"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/pkg/controller/database"
+)
+
+// Documentation holds the documentation of a Go package of a package version.
+// The docs of the exported symbols are kept with the symbols.
+type Documentation struct {
+	ImportPath string
+	Name       string
+	Synopsis   string
+	Doc        string
+	Examples   []*Example
+}
+
+// Example holds an example of a Go package, the name is empty for the package
+// examples, the name of the function or type, or <type>_<method> for the methods.
+type Example struct {
+	ImportPath string
+	Name       string
+	Suffix     string
+	Doc        string
+	Code       string
+	Output     string
+}
+
+// extractDocs extracts the package overviews and the examples of the Go sources.
+func extractDocs(fset *token.FileSet, pkgs []*doc.Package) (docs []*Documentation) {
+	docs = make([]*Documentation, len(pkgs))
+
+	for i, p := range pkgs {
+		d := &Documentation{
+			ImportPath: p.ImportPath,
+			Name:       p.Name,
+			Synopsis:   p.Synopsis(p.Doc),
+			Doc:        p.Doc,
+			Examples:   []*Example{},
+		}
+
+		examples := append([]*doc.Example{}, p.Examples...)
+		for _, f := range p.Funcs {
+			examples = append(examples, f.Examples...)
+		}
+		for _, t := range p.Types {
+			examples = append(examples, t.Examples...)
+			for _, f := range t.Funcs {
+				examples = append(examples, f.Examples...)
+			}
+			for _, m := range t.Methods {
+				examples = append(examples, m.Examples...)
+			}
+		}
+
+		for _, e := range examples {
+			code := e.Code
+			if e.Play != nil {
+				code = e.Play
+			}
+
+			d.Examples = append(d.Examples, &Example{
+				ImportPath: p.ImportPath,
+				Name:       e.Name,
+				Suffix:     e.Suffix,
+				Doc:        e.Doc,
+				Code:       printNode(fset, code),
+				Output:     e.Output,
+			})
+		}
+
+		docs[i] = d
+	}
+
+	return docs
+}
+
+// insertDocs inserts the package overviews and the examples of a pending version.
+func insertDocs(tx *sql.Tx, packageID uint64, version string, docs []*Documentation) (err error) {
+	docSt := `
+	INSERT INTO package_docs
+	(package_id, version, import_path, name, synopsis, doc)
+	VALUES
+	(?, ?, ?, ?, ?, ?)
+	`
+	exampleSt := `
+	INSERT INTO package_examples
+	(package_id, version, import_path, name, suffix, doc, code, output)
+	VALUES
+	(?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, d := range docs {
+		_, err = tx.Exec(docSt, packageID, version, d.ImportPath, d.Name, d.Synopsis, d.Doc)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert docs to package_docs table")
+			return
+		}
+
+		for _, e := range d.Examples {
+			_, err = tx.Exec(exampleSt, packageID, version, e.ImportPath, e.Name, e.Suffix, e.Doc, e.Code, e.Output)
+			if err != nil {
+				err = errors.Wrap(err, "Failed to insert examples to package_examples table")
+				return
+			}
+		}
+	}
+
+	return nil
+}
+
+// Docs returns the documentation of the Go packages of a package version.
+func Docs(packageID uint64, version string) (docs []*Documentation, err error) {
+	sqlSt := `
+	SELECT import_path, name, synopsis, doc
+	FROM package_docs
+	WHERE package_id = ? and version = ?
+	ORDER BY import_path ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs = []*Documentation{}
+	docMap := map[string]*Documentation{}
+	for rows.Next() {
+		d := &Documentation{Examples: []*Example{}}
+		err = rows.Scan(&d.ImportPath, &d.Name, &d.Synopsis, &d.Doc)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package docs query result")
+			return nil, err
+		}
+		docs = append(docs, d)
+		docMap[d.ImportPath] = d
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package docs query result")
+		return nil, err
+	}
+
+	sqlSt = `
+	SELECT import_path, name, suffix, doc, code, output
+	FROM package_examples
+	WHERE package_id = ? and version = ?
+	ORDER BY import_path ASC, name ASC, suffix ASC
+	`
+	rows, err = dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := &Example{}
+		err = rows.Scan(&e.ImportPath, &e.Name, &e.Suffix, &e.Doc, &e.Code, &e.Output)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package examples query result")
+			return nil, err
+		}
+		if d, ok := docMap[e.ImportPath]; ok {
+			d.Examples = append(d.Examples, e)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package examples query result")
+		return nil, err
+	}
+
+	return docs, nil
+}
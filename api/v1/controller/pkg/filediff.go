@@ -0,0 +1,393 @@
+package pkg
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-api/pkg/controller/storage"
+)
+
+// FileChange holds a file which differs between two versions of a package, the status
+// is one of "added", "removed" or "modified". The unified diff is computed for the text
+// files, it is omitted if the file or the total size of the diffs exceeds the limits.
+type FileChange struct {
+	Path        string
+	Status      string
+	OldSize     int64
+	NewSize     int64
+	Binary      bool
+	Diff        string
+	DiffOmitted bool
+}
+
+// FilesDiff holds the files which differ between two versions of a package, truncated
+// is set if there are more changed files than the limit.
+type FilesDiff struct {
+	Changes   []*FileChange
+	Truncated bool
+}
+
+// MetaChange holds a metadata field which differs between two versions of a package.
+// The map fields are compared per key e.g. "dependencies.<name>".
+type MetaChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// VersionMeta returns the metadata recorded at the publish of a package version.
+func VersionMeta(packageID uint64, version string) (meta *types.PackageMetaData, err error) {
+	sqlSt := `
+	SELECT meta
+	FROM package_versions
+	WHERE package_id = ? and version = ?
+	`
+	dbConn := database.Conn()
+
+	var metaJSON []byte
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&metaJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+
+	meta = &types.PackageMetaData{}
+	if len(metaJSON) == 0 {
+		return meta, nil
+	}
+
+	err = json.Unmarshal(metaJSON, meta)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to decode package metadata")
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// DiffMeta compares the metadata of two versions of a package, the name and the
+// version are not compared.
+func DiffMeta(oldMeta, newMeta *types.PackageMetaData) (changes []*MetaChange) {
+	changes = []*MetaChange{}
+
+	compare := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, &MetaChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	compareMap := func(field string, oldMap, newMap map[string]string) {
+		keys := []string{}
+		for k := range oldMap {
+			keys = append(keys, k)
+		}
+		for k := range newMap {
+			if _, ok := oldMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			compare(field+"."+k, oldMap[k], newMap[k])
+		}
+	}
+
+	compare("description", oldMeta.Description, newMeta.Description)
+	compare("license", oldMeta.License, newMeta.License)
+	compare("homepage", oldMeta.HomepageURL, newMeta.HomepageURL)
+	compare("repository", oldMeta.RepositoryURL, newMeta.RepositoryURL)
+	compare("docs", oldMeta.DocumentationURL, newMeta.DocumentationURL)
+	compare("bugsURL", oldMeta.BugsURL, newMeta.BugsURL)
+	compare("engines.go", oldMeta.Engines.Go, newMeta.Engines.Go)
+	compare("os", strings.Join(oldMeta.Os, ", "), strings.Join(newMeta.Os, ", "))
+	compare("tags", strings.Join(oldMeta.Tags, ", "), strings.Join(newMeta.Tags, ", "))
+	compareMap("commands", oldMeta.Commands, newMeta.Commands)
+	compareMap("dependencies", oldMeta.Dependencies, newMeta.Dependencies)
+
+	return changes
+}
+
+// inPath checks whether a file is the path or under the path, the empty path matches every file.
+func inPath(filePath, pathFilter string) bool {
+	return pathFilter == "" || filePath == pathFilter || strings.HasPrefix(filePath, pathFilter+"/")
+}
+
+// DiffFiles compares the file manifests of two versions of a package, optionally only
+// the files under pathFilter. The unified diffs are computed from the stored archives,
+// if withContent is set.
+func DiffFiles(packageName string, packageID uint64, fromVersion, toVersion, pathFilter string, withContent bool) (fd *FilesDiff, err error) {
+	fromFiles, err := Files(packageID, fromVersion)
+	if err != nil {
+		return
+	}
+
+	toFiles, err := Files(packageID, toVersion)
+	if err != nil {
+		return
+	}
+
+	fd = &FilesDiff{Changes: []*FileChange{}}
+
+	fromMap := map[string]*archive.File{}
+	for _, f := range fromFiles {
+		if inPath(f.Path, pathFilter) {
+			fromMap[f.Path] = f
+		}
+	}
+
+	toMap := map[string]*archive.File{}
+	for _, f := range toFiles {
+		if !inPath(f.Path, pathFilter) {
+			continue
+		}
+		toMap[f.Path] = f
+
+		old, ok := fromMap[f.Path]
+		switch {
+		case !ok:
+			fd.Changes = append(fd.Changes, &FileChange{Path: f.Path, Status: constants.FileChangeAdded, NewSize: f.Size})
+		case old.SHA256 != f.SHA256:
+			fd.Changes = append(fd.Changes, &FileChange{Path: f.Path, Status: constants.FileChangeModified, OldSize: old.Size, NewSize: f.Size})
+		}
+	}
+
+	for _, f := range fromFiles {
+		if _, ok := toMap[f.Path]; !ok && inPath(f.Path, pathFilter) {
+			fd.Changes = append(fd.Changes, &FileChange{Path: f.Path, Status: constants.FileChangeRemoved, OldSize: f.Size})
+		}
+	}
+
+	sort.Slice(fd.Changes, func(i, j int) bool {
+		return fd.Changes[i].Path < fd.Changes[j].Path
+	})
+
+	if len(fd.Changes) > constants.CompareMaxFiles {
+		fd.Changes = fd.Changes[:constants.CompareMaxFiles]
+		fd.Truncated = true
+	}
+
+	if !withContent {
+		return fd, nil
+	}
+
+	err = fd.computeDiffs(packageName, packageID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// cachedDiff holds the unified diff of a changed file, or binary if it is not a text file.
+type cachedDiff struct {
+	diff   string
+	binary bool
+}
+
+// computeDiffs computes the unified diffs of the changed files. The versions are immutable,
+// so the diffs are cached in package_file_diffs table per version pair, the files which
+// are not cached yet are read from the stored archives of both versions.
+func (fd *FilesDiff) computeDiffs(packageName string, packageID uint64, fromVersion, toVersion string) (err error) {
+	cached, err := cachedDiffs(packageID, fromVersion, toVersion)
+	if err != nil {
+		return
+	}
+
+	fromNames := map[string]bool{}
+	toNames := map[string]bool{}
+	inputSize := int64(0)
+	for _, c := range fd.Changes {
+		if c.OldSize > constants.CompareDiffFileMaxSize || c.NewSize > constants.CompareDiffFileMaxSize {
+			c.DiffOmitted = true
+			continue
+		}
+		if _, ok := cached[c.Path]; ok {
+			continue
+		}
+		if inputSize+c.OldSize+c.NewSize > constants.CompareDiffMaxInputSize {
+			c.DiffOmitted = true
+			continue
+		}
+		inputSize += c.OldSize + c.NewSize
+
+		if c.Status != constants.FileChangeAdded {
+			fromNames[c.Path] = true
+		}
+		if c.Status != constants.FileChangeRemoved {
+			toNames[c.Path] = true
+		}
+	}
+
+	fromContents, err := readArchiveFiles(packageName, fromVersion, fromNames)
+	if err != nil {
+		return
+	}
+
+	toContents, err := readArchiveFiles(packageName, toVersion, toNames)
+	if err != nil {
+		return
+	}
+
+	totalSize := 0
+	for _, c := range fd.Changes {
+		if c.DiffOmitted {
+			continue
+		}
+
+		cd, ok := cached[c.Path]
+		if !ok {
+			cd, err = diffFile(c, fromContents[c.Path], toContents[c.Path])
+			if err != nil {
+				return
+			}
+
+			err = cacheDiff(packageID, fromVersion, toVersion, c.Path, cd)
+			if err != nil {
+				return
+			}
+		}
+
+		if cd.binary {
+			c.Binary = true
+			continue
+		}
+
+		if totalSize+len(cd.diff) > constants.CompareDiffMaxTotalSize {
+			c.DiffOmitted = true
+			continue
+		}
+		totalSize += len(cd.diff)
+		c.Diff = cd.diff
+	}
+
+	return nil
+}
+
+// diffFile computes the unified diff of a changed file from its old and new contents.
+func diffFile(c *FileChange, oldContent, newContent []byte) (cd *cachedDiff, err error) {
+	if !isText(oldContent) || !isText(newContent) {
+		return &cachedDiff{binary: true}, nil
+	}
+
+	ud := difflib.UnifiedDiff{
+		A:        splitLines(oldContent),
+		B:        splitLines(newContent),
+		FromFile: "a/" + c.Path,
+		ToFile:   "b/" + c.Path,
+		Context:  3,
+	}
+	if c.Status == constants.FileChangeAdded {
+		ud.A, ud.FromFile = nil, "/dev/null"
+	}
+	if c.Status == constants.FileChangeRemoved {
+		ud.B, ud.ToFile = nil, "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(ud)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to compute the unified diff")
+		return
+	}
+
+	return &cachedDiff{diff: diff}, nil
+}
+
+// cachedDiffs returns the cached diffs of the changed files between two versions by their paths.
+func cachedDiffs(packageID uint64, fromVersion, toVersion string) (cached map[string]*cachedDiff, err error) {
+	sqlSt := `
+	SELECT path, diff, is_binary
+	FROM package_file_diffs
+	WHERE package_id = ? and from_version = ? and to_version = ?
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, fromVersion, toVersion)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	cached = map[string]*cachedDiff{}
+	for rows.Next() {
+		var filePath string
+		cd := &cachedDiff{}
+		err = rows.Scan(&filePath, &cd.diff, &cd.binary)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package file diffs query result")
+			return nil, err
+		}
+		cached[filePath] = cd
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package file diffs query result")
+		return nil, err
+	}
+
+	return cached, nil
+}
+
+// cacheDiff caches the diff of a changed file between two versions, the diff cached
+// by a concurrent comparison of the same versions is kept.
+func cacheDiff(packageID uint64, fromVersion, toVersion, filePath string, cd *cachedDiff) (err error) {
+	st := `
+	INSERT IGNORE INTO package_file_diffs
+	(package_id, from_version, to_version, path, diff, is_binary)
+	VALUES
+	(?, ?, ?, ?, ?, ?)
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, packageID, fromVersion, toVersion, filePath, cd.diff, cd.binary)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to cache the file diff to package_file_diffs table")
+		return
+	}
+
+	return nil
+}
+
+// readArchiveFiles reads some files of a package version from its stored archive.
+func readArchiveFiles(packageName, version string, names map[string]bool) (contents map[string][]byte, err error) {
+	if len(names) == 0 {
+		return map[string][]byte{}, nil
+	}
+
+	data, err := storage.OpenArchive(packageName, version)
+	if err != nil {
+		return
+	}
+	defer data.Close()
+
+	return archive.ReadFiles(data, names, constants.CompareDiffFileMaxSize)
+}
+
+// splitLines splits a text into lines keeping the line endings, the last line
+// gets one if it has not.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
+
+// isText checks whether a file content looks like text, i.e. valid UTF-8 without NUL bytes.
+func isText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) == -1
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"database/sql"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-api/pkg/controller/storage"
+)
+
+// insertFiles inserts the file manifest of a pending version.
+func insertFiles(tx *sql.Tx, packageID uint64, version string, files []*archive.File) (err error) {
+	st := `
+	INSERT INTO package_files
+	(package_id, version, path, size, mode, sha256)
+	VALUES
+	(?, ?, ?, ?, ?, ?)
+	`
+	for _, f := range files {
+		_, err = tx.Exec(st, packageID, version, f.Path, f.Size, f.Mode, f.SHA256)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert files to package_files table")
+			return
+		}
+	}
+
+	return nil
+}
+
+// Files returns the file manifest of a package version ordered by the paths, the
+// versions published before the manifest was recorded have no files.
+func Files(packageID uint64, version string) (files []*archive.File, err error) {
+	sqlSt := `
+	SELECT path, size, mode, sha256
+	FROM package_files
+	WHERE package_id = ? and version = ?
+	ORDER BY path ASC, id ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	files = []*archive.File{}
+	for rows.Next() {
+		f := &archive.File{}
+		err = rows.Scan(&f.Path, &f.Size, &f.Mode, &f.SHA256)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package files query result")
+			return nil, err
+		}
+
+		// The last entry wins if the path occurs more than once in the archive.
+		if len(files) > 0 && files[len(files)-1].Path == f.Path {
+			files[len(files)-1] = f
+			continue
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package files query result")
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// File returns a single file of the manifest of a package version, or nil if the
+// file does not exist.
+func File(packageID uint64, version, filePath string) (f *archive.File, err error) {
+	sqlSt := `
+	SELECT path, size, mode, sha256
+	FROM package_files
+	WHERE package_id = ? and version = ? and path = ?
+	ORDER BY id DESC
+	LIMIT 1
+	`
+	f = &archive.File{}
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, packageID, version, filePath).Scan(&f.Path, &f.Size, &f.Mode, &f.SHA256)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// FileContent streams the content of a file of a package version from its stored archive.
+type FileContent struct {
+	*archive.FileReader
+	data *os.File
+}
+
+// OpenFileContent opens a file of a package version in its stored archive, at most the
+// recorded size of the file is read.
+func OpenFileContent(packageName, version string, f *archive.File) (fc *FileContent, err error) {
+	data, err := storage.OpenArchive(packageName, version)
+	if err != nil {
+		return
+	}
+
+	fr, err := archive.OpenFile(data, f.Path, f.Size)
+	if err != nil {
+		data.Close()
+		return
+	}
+
+	if fr == nil {
+		data.Close()
+		err = errors.Errorf("File %s is missing in the archive of %s@%s", f.Path, packageName, version)
+		return
+	}
+
+	return &FileContent{FileReader: fr, data: data}, nil
+}
+
+// Close closes the file and the stored archive.
+func (fc *FileContent) Close() error {
+	fc.FileReader.Close()
+	return fc.data.Close()
+}
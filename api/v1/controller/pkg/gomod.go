@@ -0,0 +1,269 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/str"
+)
+
+// goVersionRe matches the release part of a Go version e.g. 1.21 of 1.21rc1.
+var goVersionRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}`)
+
+// GoModule holds the go.mod file of a package version.
+type GoModule struct {
+	Path      string
+	GoVersion string
+	Content   []byte
+	Requires  []*ModuleRequire
+	Replaces  []*ModuleReplace
+}
+
+// ModuleRequire holds a require directive of go.mod.
+type ModuleRequire struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+// ModuleReplace holds a replace directive of go.mod, the old version is empty
+// if every version of the module is replaced and the new version is empty if the
+// module is replaced by a local directory.
+type ModuleReplace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+// parseGoMod parses the go.mod file captured from the package data, it returns
+// nil if the package has no go.mod file.
+func parseGoMod(ins *archive.Inspection) (gm *GoModule, err error) {
+	if str.IsEmpty(ins.GoModFileName) {
+		return nil, nil
+	}
+
+	f, err := modfile.Parse(ins.GoModFileName, ins.GoModContent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Module == nil {
+		return nil, errors.New("go.mod: no module declaration")
+	}
+
+	gm = &GoModule{
+		Path:     f.Module.Mod.Path,
+		Content:  ins.GoModContent,
+		Requires: make([]*ModuleRequire, len(f.Require)),
+		Replaces: make([]*ModuleReplace, len(f.Replace)),
+	}
+
+	if f.Go != nil {
+		gm.GoVersion = f.Go.Version
+	}
+
+	for i, r := range f.Require {
+		gm.Requires[i] = &ModuleRequire{
+			Path:     r.Mod.Path,
+			Version:  r.Mod.Version,
+			Indirect: r.Indirect,
+		}
+	}
+
+	for i, r := range f.Replace {
+		gm.Replaces[i] = &ModuleReplace{
+			OldPath:    r.Old.Path,
+			OldVersion: r.Old.Version,
+			NewPath:    r.New.Path,
+			NewVersion: r.New.Version,
+		}
+	}
+
+	return gm, nil
+}
+
+// checkGoMod cross-checks the go.mod file against the metadata. The module path should
+// be the import path of the package, and the Go version of the go directive must
+// satisfy engines.go.
+func (v *Validation) checkGoMod() {
+	gm, err := parseGoMod(v.Inspection)
+	if err != nil {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("Problems parsing go.mod file: %s", err))
+		return
+	}
+
+	if gm == nil {
+		return
+	}
+	v.GoMod = gm
+
+	importPath := constants.PackageImportPathPrefix + v.Meta.Name
+	if gm.Path != importPath {
+		v.warn(fmt.Sprintf("The module path %s of go.mod does not match the import path %s of the package", gm.Path, importPath))
+	}
+
+	if str.IsEmpty(gm.GoVersion) {
+		return
+	}
+
+	if str.IsEmpty(v.Meta.Engines.Go) {
+		v.warn(fmt.Sprintf("engines.go is not specified, go.mod requires go %s", gm.GoVersion))
+		return
+	}
+
+	constraint, err := semver.NewConstraint(v.Meta.Engines.Go)
+	if err != nil {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("engines.go %s is not a valid version constraint", v.Meta.Engines.Go))
+		return
+	}
+
+	goVersion, err := semver.NewVersion(goVersionRe.FindString(gm.GoVersion))
+	if err != nil {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("The go directive %s of go.mod is not a valid Go version", gm.GoVersion))
+		return
+	}
+
+	if !constraint.Check(goVersion) {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("The go directive %s of go.mod contradicts engines.go %s", gm.GoVersion, v.Meta.Engines.Go))
+	}
+}
+
+// insertGoMod inserts the go.mod file of a pending version along with its require
+// and replace directives, if the package has a go.mod file.
+func insertGoMod(tx *sql.Tx, packageID uint64, version string, gm *GoModule) (err error) {
+	if gm == nil {
+		return nil
+	}
+
+	st := `
+	INSERT INTO package_go_mod
+	(package_id, version, module_path, go_version, content)
+	VALUES
+	(?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(st, packageID, version, gm.Path, gm.GoVersion, gm.Content)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to insert go.mod to package_go_mod table")
+		return
+	}
+
+	st = `
+	INSERT INTO package_module_requires
+	(package_id, version, path, module_version, indirect)
+	VALUES
+	(?, ?, ?, ?, ?)
+	`
+	for _, r := range gm.Requires {
+		_, err = tx.Exec(st, packageID, version, r.Path, r.Version, r.Indirect)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert requirements to package_module_requires table")
+			return
+		}
+	}
+
+	st = `
+	INSERT INTO package_module_replaces
+	(package_id, version, old_path, old_version, new_path, new_version)
+	VALUES
+	(?, ?, ?, ?, ?, ?)
+	`
+	for _, r := range gm.Replaces {
+		_, err = tx.Exec(st, packageID, version, r.OldPath, r.OldVersion, r.NewPath, r.NewVersion)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert replacements to package_module_replaces table")
+			return
+		}
+	}
+
+	return nil
+}
+
+// GoMod returns the go.mod file of a package version, or nil if the version
+// has no go.mod file.
+func GoMod(packageID uint64, version string) (gm *GoModule, err error) {
+	sqlSt := `
+	SELECT module_path, go_version, content
+	FROM package_go_mod
+	WHERE package_id = ? and version = ?
+	`
+	dbConn := database.Conn()
+
+	gm = &GoModule{}
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&gm.Path, &gm.GoVersion, &gm.Content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+
+	sqlSt = `
+	SELECT path, module_version, indirect
+	FROM package_module_requires
+	WHERE package_id = ? and version = ?
+	ORDER BY path ASC
+	`
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	gm.Requires = []*ModuleRequire{}
+	for rows.Next() {
+		r := &ModuleRequire{}
+		err = rows.Scan(&r.Path, &r.Version, &r.Indirect)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the module requires query result")
+			return nil, err
+		}
+		gm.Requires = append(gm.Requires, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the module requires query result")
+		return nil, err
+	}
+
+	sqlSt = `
+	SELECT old_path, old_version, new_path, new_version
+	FROM package_module_replaces
+	WHERE package_id = ? and version = ?
+	ORDER BY old_path ASC
+	`
+	rows, err = dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	gm.Replaces = []*ModuleReplace{}
+	for rows.Next() {
+		r := &ModuleReplace{}
+		err = rows.Scan(&r.OldPath, &r.OldVersion, &r.NewPath, &r.NewVersion)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the module replaces query result")
+			return nil, err
+		}
+		gm.Replaces = append(gm.Replaces, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the module replaces query result")
+		return nil, err
+	}
+
+	return gm, nil
+}
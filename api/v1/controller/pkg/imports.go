@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/pkg/controller/database"
+)
+
+// Import holds a package imported by the Go sources of a package version.
+type Import struct {
+	Path string
+	Kind string
+}
+
+// classifyImports classifies the import paths as standard library, GoPx packages or external modules.
+func classifyImports(importPaths []string) []*Import {
+	imports := make([]*Import, len(importPaths))
+	for i, importPath := range importPaths {
+		imports[i] = &Import{
+			Path: importPath,
+			Kind: helper.ImportKind(importPath),
+		}
+	}
+
+	return imports
+}
+
+// Imports returns the imports of the Go sources of a package version.
+func Imports(packageID uint64, version string) (imports []*Import, err error) {
+	sqlSt := `
+	SELECT import_path, kind
+	FROM package_imports
+	WHERE package_id = ? and version = ?
+	ORDER BY import_path ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports = []*Import{}
+	for rows.Next() {
+		imp := &Import{}
+		err = rows.Scan(&imp.Path, &imp.Kind)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package imports query result")
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package imports query result")
+		return nil, err
+	}
+
+	return imports, nil
+}
+
+// checkImports warns about the mismatches between the declared dependencies and
+// the packages actually imported by the Go sources.
+func (v *Validation) checkImports() {
+	for _, name := range v.Inspection.UnparsedFiles {
+		v.warn(fmt.Sprintf("Go source file %s could not be parsed, its imports and API are ignored", name))
+	}
+
+	used := map[string]bool{}
+	undeclared := map[string]bool{}
+
+	for _, imp := range classifyImports(v.Inspection.Imports) {
+		switch imp.Kind {
+		case constants.ImportKindGoPx:
+			name := helper.ImportedPackageName(imp.Path)
+			if name == v.Meta.Name {
+				continue
+			}
+			if _, ok := v.Meta.Dependencies[name]; ok {
+				used[name] = true
+			} else if !undeclared[name] {
+				undeclared[name] = true
+				v.warn(fmt.Sprintf("Package %s is imported but not declared in dependencies", name))
+			}
+		case constants.ImportKindModule:
+			// The sub-packages of the module itself.
+			if v.GoMod != nil && (imp.Path == v.GoMod.Path || strings.HasPrefix(imp.Path, v.GoMod.Path+"/")) {
+				continue
+			}
+			mod := importedModule(imp.Path, v.Meta.Dependencies)
+			if mod != "" {
+				used[mod] = true
+			} else {
+				v.warn(fmt.Sprintf("Module of %s is imported but not declared in dependencies", imp.Path))
+			}
+		}
+	}
+
+	// The unparsed files may import the rest of the dependencies.
+	if len(v.Inspection.UnparsedFiles) > 0 {
+		return
+	}
+
+	for _, name := range sortedStringKeys(v.Meta.Dependencies) {
+		if !used[name] {
+			v.warn(fmt.Sprintf("Dependency %s is declared but not imported", name))
+		}
+	}
+}
+
+// importedModule returns the declared module dependency which the import path belongs to,
+// or an empty string if there is none. The longest module path wins for the nested modules.
+func importedModule(importPath string, deps map[string]string) (mod string) {
+	for name := range deps {
+		if helper.DependencyKind(name) != constants.DependencyKindModule || len(name) <= len(mod) {
+			continue
+		}
+		if importPath == name || strings.HasPrefix(importPath, name+"/") {
+			mod = name
+		}
+	}
+
+	return mod
+}
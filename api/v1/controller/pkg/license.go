@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/spdx"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/str"
+)
+
+// LicenseCount holds the number of the active packages declaring a license expression.
+type LicenseCount struct {
+	License  string
+	Packages uint64
+}
+
+// checkLicense detects the license of the LICENSE file and warns if it differs from
+// the declared license, the unrecognized license texts are not reported.
+func (v *Validation) checkLicense() {
+	ins := v.Inspection
+	if str.IsEmpty(ins.LicenseFileName) {
+		return
+	}
+
+	detected := spdx.Detect(ins.LicenseContent)
+	if str.IsEmpty(detected) {
+		return
+	}
+
+	if str.IsEmpty(v.Meta.License) {
+		v.warn(fmt.Sprintf("The package license is not specified, %s is detected in %s", detected, ins.LicenseFileName))
+		return
+	}
+
+	if !spdx.Covers(v.Meta.License, detected) {
+		v.warn(fmt.Sprintf("The package license %s differs from %s detected in %s", v.Meta.License, detected, ins.LicenseFileName))
+	}
+}
+
+// Licenses counts the active packages per license expression. The licenses published
+// before the normalization are normalized here, the ones which are not valid expressions
+// are counted verbatim.
+func Licenses() (licenses []*LicenseCount, err error) {
+	sqlSt := `
+	SELECT license, COUNT(*)
+	FROM packages
+	WHERE status = ? and license IS NOT NULL and license <> ''
+	GROUP BY license
+	`
+	dbConn := database.Conn()
+
+	rows, err := dbConn.Query(sqlSt, constants.PackageStatusActive)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+	defer rows.Close()
+
+	counts := map[string]uint64{}
+	for rows.Next() {
+		var (
+			license sql.NullString
+			count   uint64
+		)
+		err = rows.Scan(&license, &count)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package licenses query result")
+			return nil, err
+		}
+
+		sLicense, err := spdx.Normalize(license.String)
+		if err != nil {
+			sLicense = license.String
+		}
+		counts[sLicense] += count
+	}
+
+	err = rows.Err()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package licenses query result")
+		return nil, err
+	}
+
+	licenses = []*LicenseCount{}
+	for license, count := range counts {
+		licenses = append(licenses, &LicenseCount{License: license, Packages: count})
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		if licenses[i].Packages != licenses[j].Packages {
+			return licenses[i].Packages > licenses[j].Packages
+		}
+		return licenses[i].License < licenses[j].License
+	})
+
+	return licenses, nil
+}
+
+// NormalizeLicenses rewrites the licenses of the packages published before the
+// normalization in the canonical SPDX form, so those are matched by the license
+// search. The licenses which are not valid expressions are kept verbatim and
+// returned as invalid.
+func NormalizeLicenses() (updated int, invalid []string, err error) {
+	sqlSt := `
+	SELECT id, name, license
+	FROM packages
+	WHERE license IS NOT NULL and license <> ''
+	`
+	dbConn := database.Conn()
+
+	rows, err := dbConn.Query(sqlSt)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+
+	type packageLicense struct {
+		id       uint64
+		license  string
+		verbatim string
+	}
+	var changed []*packageLicense
+
+	for rows.Next() {
+		var (
+			id            uint64
+			name, license string
+		)
+		err = rows.Scan(&id, &name, &license)
+		if err != nil {
+			rows.Close()
+			err = errors.Wrap(err, "Failed to scan the package licenses query result")
+			return 0, nil, err
+		}
+
+		sLicense, err := spdx.Normalize(license)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", name, license))
+			continue
+		}
+		if sLicense != license {
+			changed = append(changed, &packageLicense{id: id, license: sLicense, verbatim: license})
+		}
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package licenses query result")
+		return 0, nil, err
+	}
+
+	// The licenses updated by the publishes in the meantime are left as they are.
+	for _, pl := range changed {
+		res, err := dbConn.Exec("UPDATE packages SET license = ? WHERE id = ? and license = ?", pl.license, pl.id, pl.verbatim)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to update the package license")
+			return updated, invalid, err
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			updated++
+		}
+	}
+
+	return updated, invalid, nil
+}
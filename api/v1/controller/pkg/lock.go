@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/pkg/controller/lock"
+)
+
+// ErrPackageDeleting indicates that the package is already being deleted.
+var ErrPackageDeleting = errors.New("Package is already being deleted")
+
+// packageLocker serializes the mutations of a package, i.e. publishing a new package
+// or a new release and deleting a package.
+// Note: It only serializes the mutations within this process, the unique constraints
+// of the database still guard the mutations coming from other instances of the service.
+var packageLocker lock.Locker = lock.NewLocal()
+
+// lockPackage acquires the mutation lock of a package and returns the function which releases it.
+func lockPackage(packageName string) (unlock func(), err error) {
+	unlock, err = packageLocker.Lock(strings.ToLower(packageName))
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to lock package %s", packageName)
+		return
+	}
+
+	return unlock, nil
+}
@@ -0,0 +1,105 @@
+//go:build integration
+
+package pkg
+
+// The test needs the database and the config files of the service, which are loaded
+// relative to the working directory, so build it and run it from the repository root:
+//
+//	go test -c -tags integration -o pkg.test ./api/v1/controller/pkg
+//	GOPX_TEST_USERNAME=<existing user> ./pkg.test -test.run PublishRace -test.v
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gopx.io/gopx-api/api/v1/controller/user"
+)
+
+func testArchive(t *testing.T, files map[string]string) []byte {
+	var buff bytes.Buffer
+	gzw := gzip.NewWriter(&buff)
+	tw := tar.NewWriter(gzw)
+
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buff.Bytes()
+}
+
+// TestPublishRace publishes the same version of a new package concurrently, exactly
+// one of the publishes must succeed and the others must be rejected as client errors.
+func TestPublishRace(t *testing.T) {
+	username := os.Getenv("GOPX_TEST_USERNAME")
+	if username == "" {
+		t.Skip("GOPX_TEST_USERNAME is not set")
+	}
+
+	userRows, err := user.Query("username = ?", "id ASC", "1", "", username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userRows) == 0 {
+		t.Fatalf("User %s not found", username)
+	}
+
+	name := fmt.Sprintf("lock-race-%d", time.Now().UnixNano())
+	data := testArchive(t, map[string]string{
+		"gopx.json": fmt.Sprintf(`{"name": %q, "version": "1.0.0", "description": "Publish race test", "license": "MIT"}`, name),
+		"race.go":   "package race\n\n// Answer is the answer.\nconst Answer = 42\n",
+	})
+	defer DeletePackage(name)
+
+	const parallel = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := Publish(bytes.NewReader(data), userRows[0], false, nil)
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+
+			pErr, ok := err.(*PublishError)
+			if !ok || pErr.StatusCode < http.StatusBadRequest || pErr.StatusCode >= http.StatusInternalServerError {
+				t.Errorf("Unexpected publish error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d publishes succeeded, want 1", succeeded)
+	}
+}
@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/types"
+)
+
+// metaDecoder decodes the package metadata leniently, the well-known aliases and case
+// variants of the keys are accepted, and the unknown keys and the values of unexpected
+// types are reported as warnings instead of failing the decoding.
+type metaDecoder struct {
+	warnings []string
+}
+
+// decodePackageMeta decodes the content of the metadata file, it returns an error only
+// if the content is not a json or yaml mapping at all.
+func decodePackageMeta(fileName string, content []byte) (meta *types.PackageMetaData, warnings []string, err error) {
+	var raw interface{}
+	if fileName == "gopx.json" {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	} else {
+		err = yaml.Unmarshal(content, &raw)
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to decode %s file", fileName)
+		return
+	}
+
+	rawFields, ok := normalizeMetaValue(raw).(map[string]interface{})
+	if !ok {
+		err = errors.Errorf("The content of %s file must be a mapping", fileName)
+		return
+	}
+
+	d := &metaDecoder{}
+	fields := d.canonicalFields(rawFields)
+
+	meta = &types.PackageMetaData{
+		Name:             d.string("name", fields["name"]),
+		Version:          d.string("version", fields["version"]),
+		Description:      d.string("description", fields["description"]),
+		HomepageURL:      d.string("homepage", fields["homepage"]),
+		Tags:             d.strings("tags", fields["tags"]),
+		License:          d.string("license", fields["license"]),
+		BugsURL:          d.string("bugsURL", fields["bugsURL"]),
+		RepositoryURL:    d.string("repository", fields["repository"]),
+		DocumentationURL: d.string("docs", fields["docs"]),
+		Engines:          d.engines(fields["engines"]),
+		Os:               d.strings("os", fields["os"]),
+		Commands:         d.stringMap("commands", fields["commands"]),
+		Dependencies:     d.stringMap("dependencies", fields["dependencies"]),
+	}
+
+	return meta, d.warnings, nil
+}
+
+func (d *metaDecoder) warn(format string, args ...interface{}) {
+	d.warnings = append(d.warnings, fmt.Sprintf(format, args...))
+}
+
+// canonicalFields maps the keys of the metadata to their canonical keys.
+func (d *metaDecoder) canonicalFields(rawFields map[string]interface{}) map[string]interface{} {
+	aliases := map[string]string{}
+	for canonical, names := range constants.PackageMetaFieldAliases {
+		for _, name := range names {
+			aliases[name] = canonical
+		}
+	}
+
+	fields := map[string]interface{}{}
+	foundKeys := map[string]string{}
+	for _, key := range sortedKeys(rawFields) {
+		canonical, ok := aliases[foldMetaKey(key)]
+		if !ok {
+			d.warn("Unknown field %q is ignored", key)
+			continue
+		}
+
+		if prevKey, ok := foundKeys[canonical]; ok {
+			d.warn("Field %q is ignored, it duplicates %q", key, prevKey)
+			continue
+		}
+
+		foundKeys[canonical] = key
+		fields[canonical] = rawFields[key]
+	}
+
+	return fields
+}
+
+func (d *metaDecoder) string(field string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number, int, int64, uint64, float64, bool:
+		d.warn("Field %q should be a string, %v is read as %q", field, v, fmt.Sprint(v))
+		return fmt.Sprint(v)
+	default:
+		d.warn("Field %q should be a string, it is ignored", field)
+		return ""
+	}
+}
+
+func (d *metaDecoder) strings(field string, v interface{}) []string {
+	switch v := v.(type) {
+	case nil:
+		return []string{}
+	case string:
+		d.warn("Field %q should be a list of strings, it is read as a single item list", field)
+		return []string{v}
+	case []interface{}:
+		list := []string{}
+		for i, item := range v {
+			s := d.string(fmt.Sprintf("%s[%d]", field, i), item)
+			if s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		d.warn("Field %q should be a list of strings, it is ignored", field)
+		return []string{}
+	}
+}
+
+func (d *metaDecoder) engines(v interface{}) (engines types.PackageMetaDataEngines) {
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			if foldMetaKey(key) != "go" {
+				d.warn("Unknown field %q is ignored", "engines."+key)
+				continue
+			}
+			engines.Go = d.string("engines.go", v[key])
+		}
+	default:
+		d.warn("Field %q should be a mapping, it is ignored", "engines")
+	}
+
+	return
+}
+
+func (d *metaDecoder) stringMap(field string, v interface{}) map[string]string {
+	m := map[string]string{}
+
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			m[key] = d.string(field+"."+key, v[key])
+		}
+	default:
+		d.warn("Field %q should be a mapping, it is ignored", field)
+	}
+
+	return m
+}
+
+// normalizeMetaValue converts the yaml mappings to the json ones, so that
+// both of the formats are decoded the same way.
+func normalizeMetaValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeMetaValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for key, val := range v {
+			m[key] = normalizeMetaValue(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, val := range v {
+			l[i] = normalizeMetaValue(val)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+func foldMetaKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.Replace(key, "-", "", -1)
+	return strings.Replace(key, "_", "", -1)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
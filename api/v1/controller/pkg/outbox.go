@@ -0,0 +1,946 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/user"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/config"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-api/pkg/controller/storage"
+	"gopx.io/gopx-api/pkg/controller/vcs"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-common/misc"
+)
+
+// versionDataTables holds the tables which keep the data of a package version,
+// keyed by package_id and version.
+var versionDataTables = []string{"package_versions", "package_readme", "package_commands", "package_dependencies", "package_imports", "package_go_mod", "package_module_requires", "package_module_replaces", "package_symbols", "package_docs", "package_examples", "package_files", "package_changelog", "package_license"}
+
+// packageDataTables holds the tables which keep the data of a package, keyed by package_id.
+var packageDataTables = append([]string{"package_tags", "package_downloads", "package_file_diffs"}, versionDataTables...)
+
+// OutboxEntry represents a pending vcs registry operation of a package.
+// Every publish, delete and owner change is first recorded as an outbox entry together with
+// the database changes, and then applied to the vcs registry by ProcessOutboxEntry,
+// either immediately or later by the reconciler.
+type OutboxEntry struct {
+	ID          uint64
+	PackageID   uint64
+	PackageName string
+	Version     string
+	Operation   string
+	Status      string
+	Attempts    int
+	LastError   string
+	SHA256      string
+}
+
+// RunReconciler periodically retries the unfinished outbox entries until they
+// are committed, or compensates them once they exhaust all the attempts.
+// It never returns.
+func RunReconciler() {
+	for {
+		ids, err := dueOutboxEntries()
+		if err != nil {
+			log.Error("Error %s", err)
+		}
+
+		for _, id := range ids {
+			_, err := ProcessOutboxEntry(id)
+			if err != nil {
+				log.Error("Error %s", err)
+			}
+		}
+
+		time.Sleep(constants.OutboxReconcileInterval)
+	}
+}
+
+// ProcessOutboxEntry applies an outbox entry to the vcs registry and moves it
+// forward through its states. It returns the resulting status of the entry.
+// A vcs failure is not returned as an error, it is recorded on the entry instead
+// so that the reconciler can retry it.
+func ProcessOutboxEntry(entryID uint64) (status string, err error) {
+	claimed, err := claimOutboxEntry(entryID)
+	if err != nil {
+		return
+	}
+
+	entry, err := queryOutboxEntry(entryID)
+	if err != nil {
+		return
+	}
+
+	// Another worker is processing the entry at the moment.
+	if !claimed {
+		return entry.Status, nil
+	}
+	defer releaseOutboxEntry(entryID)
+
+	switch entry.Operation {
+	case constants.OutboxOperationRegister:
+		return processRegisterEntry(entry)
+	case constants.OutboxOperationDelete:
+		return processDeleteEntry(entry)
+	case constants.OutboxOperationSyncOwner:
+		return processSyncOwnerEntry(entry)
+	default:
+		err = errors.Errorf("Unknown outbox operation %s", entry.Operation)
+		return
+	}
+}
+
+func processRegisterEntry(entry *OutboxEntry) (status string, err error) {
+	if entry.Status == constants.VersionStatusPending {
+		err = registerToVCS(entry)
+		if errors.Cause(err) == vcs.ErrPackageExists {
+			err = checkRegisteredDigest(entry)
+		}
+		if err != nil {
+			return failOutboxEntry(entry, err)
+		}
+
+		stored, err := markOutboxEntryStored(entry)
+		if err != nil {
+			return "", err
+		}
+
+		// The entry was cancelled by a delete of the package in the meantime.
+		if !stored {
+			return constants.VersionStatusFailed, nil
+		}
+		entry.Status = constants.VersionStatusStored
+	}
+
+	if entry.Status == constants.VersionStatusStored {
+		err = commitRegisterEntry(entry)
+		if err != nil {
+			return failOutboxEntry(entry, err)
+		}
+		entry.Status = constants.VersionStatusCommitted
+	}
+
+	return entry.Status, nil
+}
+
+func processDeleteEntry(entry *OutboxEntry) (status string, err error) {
+	if entry.Status == constants.VersionStatusPending {
+		// A register entry of the package which was claimed before the delete may still
+		// reach the vcs registry, so the delete waits for it to finish.
+		inFlight, err := registerInFlight(entry.PackageID)
+		if err != nil {
+			return "", err
+		}
+		if inFlight {
+			return entry.Status, nil
+		}
+
+		err = vcs.DeletePackage(entry.PackageName)
+		if err != nil && errors.Cause(err) != vcs.ErrPackageNotFound {
+			return failOutboxEntry(entry, err)
+		}
+
+		_, err = markOutboxEntryStored(entry)
+		if err != nil {
+			return "", err
+		}
+		entry.Status = constants.VersionStatusStored
+	}
+
+	if entry.Status == constants.VersionStatusStored {
+		err = commitDeleteEntry(entry)
+		if err != nil {
+			return entry.Status, err
+		}
+		entry.Status = constants.VersionStatusCommitted
+
+		err = storage.RemovePackage(entry.PackageName)
+		if err != nil {
+			log.Error("Error %s", err)
+		}
+	}
+
+	return entry.Status, nil
+}
+
+// processSyncOwnerEntry sends the current owner info of a package to the vcs registry,
+// the packages which are not active are skipped as those have no owner to sync there.
+func processSyncOwnerEntry(entry *OutboxEntry) (status string, err error) {
+	pkgRows, err := Query("id = ?", "id ASC", "1", "", entry.PackageID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read package data")
+		return
+	}
+
+	if len(pkgRows) > 0 && pkgRows[0].Status == constants.PackageStatusActive {
+		owner, err := vcsOwner(pkgRows[0])
+		if err != nil {
+			return failOutboxEntry(entry, err)
+		}
+
+		err = vcs.UpdatePackageOwner(entry.PackageName, owner)
+		if err != nil {
+			return failOutboxEntry(entry, errors.Wrap(err, "Failed to sync owner info of package to vcs registry"))
+		}
+	}
+
+	st := `
+	UPDATE package_outbox
+	SET status = ?, last_error = NULL
+	WHERE id = ?
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, constants.VersionStatusCommitted, entry.ID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	return constants.VersionStatusCommitted, nil
+}
+
+// vcsOwner returns the current owner info of a package for the vcs registry.
+func vcsOwner(pr *QueryRow) (owner *vcs.PackageOwner, err error) {
+	userRows, err := user.Query("username = ?", "id ASC", "1", "", pr.OwnerUsername)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read package owner data")
+		return
+	}
+	if len(userRows) == 0 {
+		err = errors.Errorf("Owner %s of package %s not found", pr.OwnerUsername, pr.Name)
+		return
+	}
+	ownerInfo := userRows[0]
+
+	owner = &vcs.PackageOwner{
+		Name:        ownerInfo.Name,
+		PublicEmail: misc.TerOpt(ownerInfo.IsPublicEmail, ownerInfo.Email, "").(string),
+		Username:    ownerInfo.Username,
+	}
+
+	return owner, nil
+}
+
+func registerToVCS(entry *OutboxEntry) (err error) {
+	pkgRows, err := Query("id = ?", "id ASC", "1", "", entry.PackageID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read package data")
+		return
+	}
+	if len(pkgRows) == 0 {
+		err = errors.Errorf("Package %s not found", entry.PackageName)
+		return
+	}
+
+	owner, err := vcsOwner(pkgRows[0])
+	if err != nil {
+		return
+	}
+
+	data, err := storage.OpenArchive(entry.PackageName, entry.Version)
+	if err != nil {
+		return
+	}
+	defer data.Close()
+
+	vcsMeta := &vcs.PackageMeta{
+		Type:    vcs.PackageTypePublic,
+		Name:    entry.PackageName,
+		Version: entry.Version,
+		Owner:   *owner,
+	}
+	err = vcs.RegisterPackage(vcsMeta, data)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to register package to vcs registry")
+		return
+	}
+
+	return nil
+}
+
+// checkRegisteredDigest checks whether the version which the vcs registry already holds
+// can be the archive of the entry i.e. a retry of the entry itself. It can not if an
+// earlier register entry of the same version since the last delete of the package
+// recorded a different archive digest, that one may have reached the vcs registry before
+// it was compensated.
+func checkRegisteredDigest(entry *OutboxEntry) (err error) {
+	if entry.SHA256 == "" {
+		return nil
+	}
+
+	sqlSt := `
+	SELECT COUNT(*)
+	FROM package_outbox
+	WHERE package_name = ? and version = ? and operation = ? and id < ? and sha256 <> ? and id > (
+		SELECT COALESCE(MAX(id), 0)
+		FROM package_outbox
+		WHERE package_name = ? and operation = ? and status = ?
+	)
+	`
+	var count int
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(
+		sqlSt,
+		entry.PackageName,
+		entry.Version,
+		constants.OutboxOperationRegister,
+		entry.ID,
+		entry.SHA256,
+		entry.PackageName,
+		constants.OutboxOperationDelete,
+		constants.VersionStatusCommitted,
+	).Scan(&count)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+
+	if count > 0 {
+		return errors.Errorf("The vcs registry holds a different archive of %s@%s", entry.PackageName, entry.Version)
+	}
+
+	return nil
+}
+
+// markOutboxEntryStored records that the vcs registry has accepted the entry, it
+// returns false if the entry is not pending anymore i.e. it was cancelled.
+func markOutboxEntryStored(entry *OutboxEntry) (ok bool, err error) {
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	st := `
+	UPDATE package_outbox
+	SET status = ?, last_error = NULL
+	WHERE id = ? and status = ?
+	`
+	r, err := tx.Exec(st, constants.VersionStatusStored, entry.ID, constants.VersionStatusPending)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	if n, _ := r.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	if entry.Operation == constants.OutboxOperationRegister {
+		st = `
+		UPDATE package_versions
+		SET status = ?
+		WHERE package_id = ? and version = ?
+		`
+		_, err = tx.Exec(st, constants.VersionStatusStored, entry.PackageID, entry.Version)
+		if err != nil {
+			tx.Rollback()
+			err = errors.Wrap(err, "Failed to update package_versions table")
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes")
+		return
+	}
+
+	return true, nil
+}
+
+// commitRegisterEntry commits a stored version. If the version is greater than the
+// latest release of the package, the version becomes the latest release by applying
+// the metadata recorded with it, otherwise only the version itself is committed.
+func commitRegisterEntry(entry *OutboxEntry) (err error) {
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	sqlSt := `
+	SELECT meta
+	FROM package_versions
+	WHERE package_id = ? and version = ?
+	FOR UPDATE
+	`
+	var metaJSON []byte
+	err = tx.QueryRow(sqlSt, entry.PackageID, entry.Version).Scan(&metaJSON)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to read version metadata")
+		return
+	}
+
+	meta := types.PackageMetaData{}
+	err = json.Unmarshal(metaJSON, &meta)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to decode version metadata")
+		return
+	}
+
+	sqlSt = `
+	SELECT status, latest_version
+	FROM packages
+	WHERE id = ?
+	FOR UPDATE
+	`
+	var (
+		pkgStatus     string
+		latestVersion sql.NullString
+	)
+	err = tx.QueryRow(sqlSt, entry.PackageID).Scan(&pkgStatus, &latestVersion)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to read package data")
+		return
+	}
+
+	// The first version of a package is always the latest one, the package being
+	// deleted is left as is.
+	isLatest := pkgStatus == constants.PackageStatusPending
+	if pkgStatus == constants.PackageStatusActive {
+		isLatest, err = helper.IsNewerVersion(meta.Version, latestVersion.String)
+		if err != nil {
+			tx.Rollback()
+			err = errors.Wrap(err, "Failed to compare with the latest version")
+			return
+		}
+	}
+
+	if isLatest {
+		err = applyLatestRelease(tx, entry.PackageID, &meta)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+
+	st := `
+	UPDATE package_versions
+	SET status = ?
+	WHERE package_id = ? and version = ?
+	`
+	_, err = tx.Exec(st, constants.VersionStatusCommitted, entry.PackageID, entry.Version)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update package_versions table")
+		return
+	}
+
+	st = `
+	UPDATE package_outbox
+	SET status = ?
+	WHERE id = ?
+	`
+	_, err = tx.Exec(st, constants.VersionStatusCommitted, entry.ID)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes")
+		return
+	}
+
+	return nil
+}
+
+// applyLatestRelease updates a package with the metadata of its latest release.
+func applyLatestRelease(tx *sql.Tx, packageID uint64, meta *types.PackageMetaData) (err error) {
+	st := `
+	UPDATE packages
+	SET status = ?, latest_version = ?, description = ?, license = ?, homepage_url = ?, repository_url = ?, documentation_url = ?, bugs_url = ?, engines_go = ?, os = ?
+	WHERE id = ?
+	`
+	_, err = tx.Exec(
+		st,
+		constants.PackageStatusActive,
+		meta.Version,
+		meta.Description,
+		meta.License,
+		meta.HomepageURL,
+		meta.RepositoryURL,
+		meta.DocumentationURL,
+		meta.BugsURL,
+		meta.Engines.Go,
+		strings.Join(meta.Os, ", "),
+		packageID,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update package data to packages table")
+		return
+	}
+
+	st = `
+	DELETE FROM package_tags
+	WHERE package_id = ?
+	`
+	_, err = tx.Exec(st, packageID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to delete existing tags from package_tags table")
+		return
+	}
+
+	st = `
+	INSERT INTO package_tags
+	(package_id, tag)
+	VALUES
+	(?, ?)
+	`
+	prepSt, err := tx.Prepare(st)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to create prepared statement")
+		return
+	}
+	defer prepSt.Close()
+
+	for _, v := range meta.Tags {
+		_, err = prepSt.Exec(packageID, v)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert tags to package_tags table")
+			return
+		}
+	}
+
+	return nil
+}
+
+// commitDeleteEntry removes the package data from database once the vcs
+// registry has deleted the package.
+func commitDeleteEntry(entry *OutboxEntry) (err error) {
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	st := `
+	DELETE FROM packages
+	WHERE id = ?
+	`
+	_, err = tx.Exec(st, entry.PackageID)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to delete package data from packages table")
+		return
+	}
+
+	for _, table := range packageDataTables {
+		st = `
+		DELETE FROM ` + table + `
+		WHERE package_id = ?
+		`
+		_, err = tx.Exec(st, entry.PackageID)
+		if err != nil {
+			tx.Rollback()
+			err = errors.Wrapf(err, "Failed to delete package data from %s table", table)
+			return
+		}
+	}
+
+	st = `
+	UPDATE package_outbox
+	SET status = ?
+	WHERE id = ?
+	`
+	_, err = tx.Exec(st, constants.VersionStatusCommitted, entry.ID)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes of deleting package")
+		return
+	}
+
+	return nil
+}
+
+// failOutboxEntry records a failed attempt of an entry and schedules the next
+// one with exponential backoff, or compensates the entry when it has exhausted
+// all the attempts. A stored register entry is never compensated, the vcs registry
+// already holds the version, so it is retried until it commits.
+func failOutboxEntry(entry *OutboxEntry, cause error) (status string, err error) {
+	entry.Attempts++
+	stored := entry.Operation == constants.OutboxOperationRegister && entry.Status == constants.VersionStatusStored
+	if entry.Attempts >= constants.OutboxMaxAttempts && !stored {
+		return compensateOutboxEntry(entry, cause)
+	}
+
+	return scheduleOutboxEntry(entry, cause)
+}
+
+// scheduleOutboxEntry records the cause of a failed attempt and schedules the next one.
+func scheduleOutboxEntry(entry *OutboxEntry, cause error) (status string, err error) {
+	backoff := constants.OutboxReconcileInterval << uint(entry.Attempts)
+	if backoff <= 0 || backoff > constants.OutboxMaxBackoff {
+		backoff = constants.OutboxMaxBackoff
+	}
+
+	st := `
+	UPDATE package_outbox
+	SET attempts = ?, last_error = ?, next_attempt_at = ?
+	WHERE id = ?
+	`
+	dbConn := database.Conn()
+	_, err = dbConn.Exec(st, entry.Attempts, cause.Error(), time.Now().Add(backoff), entry.ID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	return entry.Status, nil
+}
+
+// compensateOutboxEntry reverts the database changes of an entry which can not
+// be applied to the vcs registry.
+func compensateOutboxEntry(entry *OutboxEntry, cause error) (status string, err error) {
+	if entry.Operation == constants.OutboxOperationRegister {
+		err = unregisterFromVCS(entry)
+		if err != nil {
+			return scheduleOutboxEntry(entry, err)
+		}
+	}
+
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	switch entry.Operation {
+	case constants.OutboxOperationRegister:
+		for _, table := range versionDataTables {
+			st := `
+			DELETE FROM ` + table + `
+			WHERE package_id = ? and version = ?
+			`
+			_, err = tx.Exec(st, entry.PackageID, entry.Version)
+			if err != nil {
+				tx.Rollback()
+				err = errors.Wrapf(err, "Failed to delete version data from %s table", table)
+				return
+			}
+		}
+
+		// A package which was never committed is removed entirely to free up the name.
+		st := `
+		DELETE FROM packages
+		WHERE id = ? and status = ?
+		`
+		r, err := tx.Exec(st, entry.PackageID, constants.PackageStatusPending)
+		if err != nil {
+			tx.Rollback()
+			err = errors.Wrap(err, "Failed to delete package data from packages table")
+			return "", err
+		}
+
+		if n, _ := r.RowsAffected(); n > 0 {
+			st = `
+			DELETE FROM package_tags
+			WHERE package_id = ?
+			`
+			_, err = tx.Exec(st, entry.PackageID)
+			if err != nil {
+				tx.Rollback()
+				err = errors.Wrap(err, "Failed to delete package data from package_tags table")
+				return "", err
+			}
+		}
+	case constants.OutboxOperationDelete:
+		st := `
+		UPDATE packages
+		SET status = ?
+		WHERE id = ?
+		`
+		_, err = tx.Exec(st, constants.PackageStatusActive, entry.PackageID)
+		if err != nil {
+			tx.Rollback()
+			err = errors.Wrap(err, "Failed to update package status to packages table")
+			return
+		}
+	}
+
+	st := `
+	UPDATE package_outbox
+	SET status = ?, attempts = ?, last_error = ?
+	WHERE id = ?
+	`
+	_, err = tx.Exec(st, constants.VersionStatusFailed, entry.Attempts, cause.Error(), entry.ID)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update package_outbox table")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes of compensation")
+		return
+	}
+
+	if entry.Operation == constants.OutboxOperationRegister {
+		err = storage.RemoveArchive(entry.PackageName, entry.Version)
+		if err != nil {
+			log.Error("Error %s", err)
+		}
+	}
+
+	return constants.VersionStatusFailed, nil
+}
+
+// unregisterFromVCS removes a package which was never committed and has no other
+// registered versions from the vcs registry before its register entry is compensated,
+// the registration may have reached the vcs registry in spite of the failure. The single versions of a committed package can not
+// be removed, those are guarded by the recorded digests instead, see checkRegisteredDigest.
+func unregisterFromVCS(entry *OutboxEntry) (err error) {
+	sqlSt := `
+	SELECT COUNT(*)
+	FROM packages
+	WHERE id = ? and status = ? and NOT EXISTS (
+		SELECT id
+		FROM package_outbox
+		WHERE package_id = ? and operation = ? and status IN (?, ?, ?) and id <> ?
+	)
+	`
+	var count int
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(
+		sqlSt,
+		entry.PackageID,
+		constants.PackageStatusPending,
+		entry.PackageID,
+		constants.OutboxOperationRegister,
+		constants.VersionStatusPending,
+		constants.VersionStatusStored,
+		constants.VersionStatusCommitted,
+		entry.ID,
+	).Scan(&count)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	err = vcs.DeletePackage(entry.PackageName)
+	if err != nil && errors.Cause(err) != vcs.ErrPackageNotFound {
+		return errors.Wrap(err, "Failed to remove package from vcs registry")
+	}
+
+	return nil
+}
+
+// insertOutboxEntry records an operation of a package along with the instance which
+// stored the archive of the package version, see leaseScope, and the digest of the
+// archive for the register entries, see checkRegisteredDigest.
+func insertOutboxEntry(tx *sql.Tx, packageID uint64, packageName, version, operation, sha256 string) (entryID uint64, err error) {
+	st := `
+	INSERT INTO package_outbox
+	(package_id, package_name, version, operation, status, attempts, next_attempt_at, instance_id, sha256)
+	VALUES
+	(?, ?, ?, ?, ?, 0, ?, ?, ?)
+	`
+	r, err := tx.Exec(st, packageID, packageName, version, operation, constants.VersionStatusPending, time.Now(), config.Service.InstanceID, sql.NullString{String: sha256, Valid: sha256 != ""})
+	if err != nil {
+		err = errors.Wrap(err, "Failed to insert entry to package_outbox table")
+		return
+	}
+
+	id, err := r.LastInsertId()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to retrive the last insert ID")
+		return
+	}
+
+	return uint64(id), nil
+}
+
+func queryOutboxEntry(entryID uint64) (entry *OutboxEntry, err error) {
+	sqlSt := `
+	SELECT id, package_id, package_name, version, operation, status, attempts, last_error, sha256
+	FROM package_outbox
+	WHERE id = ?
+	`
+	var lastError, sha256 sql.NullString
+
+	entry = &OutboxEntry{}
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, entryID).Scan(
+		&entry.ID,
+		&entry.PackageID,
+		&entry.PackageName,
+		&entry.Version,
+		&entry.Operation,
+		&entry.Status,
+		&entry.Attempts,
+		&lastError,
+		&sha256,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read entry from package_outbox table")
+		return nil, err
+	}
+	entry.LastError = lastError.String
+	entry.SHA256 = sha256.String
+
+	return entry, nil
+}
+
+// cancelRegisterEntries fails the unfinished register entries of a package which is
+// being deleted. An entry which is being processed at the moment notices it when
+// it tries to move forward, see markOutboxEntryStored.
+func cancelRegisterEntries(tx *sql.Tx, packageID uint64) (err error) {
+	st := `
+	UPDATE package_outbox
+	SET status = ?, last_error = ?
+	WHERE package_id = ? and operation = ? and status IN (?, ?)
+	`
+	_, err = tx.Exec(st, constants.VersionStatusFailed, "The package was deleted", packageID, constants.OutboxOperationRegister, constants.VersionStatusPending, constants.VersionStatusStored)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to cancel the register entries of package_outbox table")
+		return
+	}
+
+	return nil
+}
+
+// registerInFlight checks whether a register entry of a package is being processed.
+func registerInFlight(packageID uint64) (ok bool, err error) {
+	sqlSt := `
+	SELECT COUNT(*)
+	FROM package_outbox
+	WHERE package_id = ? and operation = ? and locked_until >= ?
+	`
+	var count int
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, packageID, constants.OutboxOperationRegister, time.Now()).Scan(&count)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return
+	}
+
+	return count > 0, nil
+}
+
+// leaseScope returns the condition which limits the register entries to the instance
+// which stored their archives, unless the storage is shared by all the instances. An
+// archive missing on another instance would otherwise compensate a valid version.
+func leaseScope() (clause string, args []interface{}) {
+	if config.Storage.Shared {
+		return "", nil
+	}
+
+	return " and (operation <> ? or instance_id = ?)", []interface{}{constants.OutboxOperationRegister, config.Service.InstanceID}
+}
+
+// claimOutboxEntry takes a lease on an unfinished entry so that the request
+// handlers and the reconciler never apply the same entry concurrently.
+func claimOutboxEntry(entryID uint64) (ok bool, err error) {
+	scope, scopeArgs := leaseScope()
+	st := `
+	UPDATE package_outbox
+	SET locked_until = ?
+	WHERE id = ? and status IN (?, ?) and (locked_until IS NULL or locked_until < ?)` + scope
+	now := time.Now()
+
+	args := append([]interface{}{now.Add(constants.OutboxLeaseDuration), entryID, constants.VersionStatusPending, constants.VersionStatusStored, now}, scopeArgs...)
+
+	dbConn := database.Conn()
+	r, err := dbConn.Exec(st, args...)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to claim package_outbox entry")
+		return
+	}
+
+	n, err := r.RowsAffected()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to claim package_outbox entry")
+		return
+	}
+
+	return n == 1, nil
+}
+
+func releaseOutboxEntry(entryID uint64) {
+	st := `
+	UPDATE package_outbox
+	SET locked_until = NULL
+	WHERE id = ?
+	`
+	dbConn := database.Conn()
+	_, err := dbConn.Exec(st, entryID)
+	if err != nil {
+		log.Error("Error %s", errors.Wrap(err, "Failed to release package_outbox entry"))
+	}
+}
+
+func dueOutboxEntries() (ids []uint64, err error) {
+	scope, scopeArgs := leaseScope()
+	sqlSt := `
+	SELECT id
+	FROM package_outbox
+	WHERE status IN (?, ?) and next_attempt_at <= ? and (locked_until IS NULL or locked_until < ?)` + scope + `
+	ORDER BY id ASC
+	LIMIT ?
+	`
+	now := time.Now()
+
+	args := append([]interface{}{constants.VersionStatusPending, constants.VersionStatusStored, now, now}, scopeArgs...)
+	args = append(args, constants.OutboxBatchSize)
+
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, args...)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			err = errors.Wrap(err, "Failed to scan the package_outbox query result")
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package_outbox query result")
+		return nil, err
+	}
+
+	return ids, nil
+}
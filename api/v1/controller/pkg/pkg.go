@@ -1,24 +1,26 @@
 package pkg
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
 	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/spdx"
 	"gopx.io/gopx-api/api/v1/controller/user"
 	"gopx.io/gopx-api/api/v1/types"
 	"gopx.io/gopx-api/pkg/controller/database"
-	"gopx.io/gopx-api/pkg/controller/vcs"
+	"gopx.io/gopx-api/pkg/controller/storage"
 	"gopx.io/gopx-common/arr"
-	"gopx.io/gopx-common/fs"
-	"gopx.io/gopx-common/misc"
+	"gopx.io/gopx-common/log"
 	"gopx.io/gopx-common/str"
 )
 
@@ -29,6 +31,14 @@ import (
 //	2. desc
 //	3. tag
 //	4. or any combination of them with comma separation.
+// Possible values of 'has' qualifier:
+//	1. command:<name>, the latest version of the package has the command.
+// Possible values of 'symbol' qualifier:
+//	1. <name>, the latest version of the package exports a symbol with the name.
+//	2. <type>.<method>, the latest version of the package exports the method.
+// Possible values of 'license' qualifier:
+//	1. <SPDX identifier or informal name>, the license expression of the package includes the license,
+//	   or its "only" or "or later" variant.
 // Note: Replace a whitespace with '+' character in query values.
 type SearchQuery struct {
 	SearchTerm string
@@ -37,6 +47,9 @@ type SearchQuery struct {
 	Updated    string
 	Downloads  string
 	Owner      string
+	Has        string
+	Symbol     string
+	License    string
 }
 
 // QueryRow represents a single row to query a package data from database.
@@ -44,7 +57,11 @@ type QueryRow struct {
 	ID               uint64
 	Name             string
 	OwnerUsername    string
+	Status           string
 	Downloads        uint64
+	DependentsCount  uint64
+	SemverPolicy     string
+	BuildPolicy      string
 	LatestVersion    string
 	PublishedAt      time.Time
 	LastReleasedAt   time.Time
@@ -67,16 +84,38 @@ type VersionHistory struct {
 
 // SingleVersion holds info of a single version.
 type SingleVersion struct {
-	Version    string
-	ReleasedAT time.Time
+	PackageID    uint64
+	PackageName  string
+	Version      string
+	Status       string
+	SHA256       string
+	SHA512       string
+	BuildStatus  string
+	NotesExcerpt string
+	ReleasedAT   time.Time
 }
 
-// ReadmeData holds the package README content in base64 format.
+// VersionDigest holds the recorded digests of the archive of a single version.
+type VersionDigest struct {
+	PackageName string
+	Version     string
+	SHA256      string
+	SHA512      string
+}
+
+// ReadmeData holds the package README content in base64 format, along with the raw content.
 type ReadmeData struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
 	Size    uint64 `json:"size"`
 	Content string `json:"content"`
+	Raw     []byte `json:"-"`
+}
+
+// MutationData holds package settings mutation data.
+type MutationData struct {
+	SemverPolicy *string
+	BuildPolicy  *string
 }
 
 // Search searches packages according to the search query and returns a slice containing
@@ -98,6 +137,9 @@ func Search(q *SearchQuery, pc *helper.PaginationConfig, sc *helper.SortingConfi
 	q.Updated = helper.DecodeQueryValue(q.Updated)
 	q.Downloads = helper.DecodeQueryValue(q.Downloads)
 	q.Owner = helper.DecodeQueryValue(q.Owner)
+	q.Has = helper.DecodeQueryValue(q.Has)
+	q.Symbol = helper.DecodeQueryValue(q.Symbol)
+	q.License = helper.DecodeQueryValue(q.License)
 
 	if str.IsEmpty(q.In) {
 		q.In = strings.Join(constants.PackageQueryIns, ",")
@@ -158,6 +200,47 @@ func Search(q *SearchQuery, pc *helper.PaginationConfig, sc *helper.SortingConfi
 		placeholderValues = append(placeholderValues, q.Owner)
 	}
 
+	// Add filters for q.Has
+	if strings.HasPrefix(q.Has, "command:") {
+		whereClauses = append(whereClauses, "EXISTS (SELECT 1 FROM package_commands WHERE package_commands.package_id = packages.id and package_commands.version = packages.latest_version and package_commands.name = ?)")
+		placeholderValues = append(placeholderValues, strings.TrimPrefix(q.Has, "command:"))
+	}
+
+	// Add filters for q.Symbol
+	if !str.IsEmpty(q.Symbol) {
+		symbolSt := "EXISTS (SELECT 1 FROM package_symbols WHERE package_symbols.package_id = packages.id and package_symbols.version = packages.latest_version and package_symbols.name = ?%s)"
+		if parts := strings.SplitN(q.Symbol, ".", 2); len(parts) == 2 {
+			whereClauses = append(whereClauses, fmt.Sprintf(symbolSt, " and package_symbols.receiver = ?"))
+			placeholderValues = append(placeholderValues, parts[1], parts[0])
+		} else {
+			whereClauses = append(whereClauses, fmt.Sprintf(symbolSt, ""))
+			placeholderValues = append(placeholderValues, q.Symbol)
+		}
+	}
+
+	// Add filters for q.License, a single license matches its "only" and "or later"
+	// variants like spdx.Covers. The licenses published before the normalization are
+	// matched verbatim, those are normalized by the normalize-licenses admin command.
+	if !str.IsEmpty(q.License) {
+		license := q.License
+		if sLicense, err := spdx.Normalize(q.License); err == nil {
+			license = sLicense
+		}
+
+		variants := []string{license}
+		if ids := spdx.IDs(license); len(ids) == 1 && ids[0] == license {
+			variants = spdx.Variants(license)
+		}
+
+		licenseClauses := []string{"license = ?"}
+		placeholderValues = append(placeholderValues, q.License)
+		for _, v := range variants {
+			licenseClauses = append(licenseClauses, "CONCAT(' ', REPLACE(REPLACE(license, '(', ' '), ')', ' '), ' ') LIKE ?")
+			placeholderValues = append(placeholderValues, "% "+helper.EscapeLikeValue(v)+" %")
+		}
+		whereClauses = append(whereClauses, "("+strings.Join(licenseClauses, " or ")+")")
+	}
+
 	sanSortByCols := helper.SanitizeSortByCols(sc.SortBy, constants.PackageSortByCols)
 	if len(sanSortByCols) == 0 {
 		sanSortByCols = []string{constants.PackageDefaultSortByCol}
@@ -198,7 +281,7 @@ func Search(q *SearchQuery, pc *helper.PaginationConfig, sc *helper.SortingConfi
 func Query(whereClause, sortBy, limit, offset string, args ...interface{}) (pkgRows []*QueryRow, err error) {
 	sqlSt := `
 	SELECT DISTINCT
-	packages.id, packages.name, packages.owner_username, packages.downloads, packages.latest_version, packages.published_at, packages.last_released_at,
+	packages.id, packages.name, packages.owner_username, packages.status, packages.downloads, packages.dependents_count, packages.semver_policy, packages.build_policy, packages.latest_version, packages.published_at, packages.last_released_at,
 	packages.description, packages.license, packages.homepage_url, packages.repository_url, packages.documentation_url, packages.bugs_url, packages.engines_go, packages.os
 	FROM
 	(SELECT
@@ -209,7 +292,7 @@ func Query(whereClause, sortBy, limit, offset string, args ...interface{}) (pkgR
 	FROM
 	users
 	INNER JOIN
-	(SELECT packages.*, COUNT(package_downloads.id) AS downloads FROM packages LEFT JOIN package_downloads ON packages.id = package_downloads.package_id GROUP BY packages.id) AS packages
+	(SELECT packages.*, COUNT(package_downloads.id) AS downloads, (` + dependentsCountSt + `) AS dependents_count FROM packages LEFT JOIN package_downloads ON packages.id = package_downloads.package_id GROUP BY packages.id) AS packages
 	ON
 	users.id = packages.owner_id ORDER BY packages.id) AS packages
 	LEFT JOIN
@@ -250,7 +333,11 @@ func Query(whereClause, sortBy, limit, offset string, args ...interface{}) (pkgR
 		id               uint64
 		name             string
 		ownerUsername    string
+		status           string
 		downloads        uint64
+		dependentsCount  uint64
+		semverPolicy     string
+		buildPolicy      string
 		latestVersion    string
 		publishedAt      time.Time
 		lastReleasedAt   time.Time
@@ -269,7 +356,11 @@ func Query(whereClause, sortBy, limit, offset string, args ...interface{}) (pkgR
 			&id,
 			&name,
 			&ownerUsername,
+			&status,
 			&downloads,
+			&dependentsCount,
+			&semverPolicy,
+			&buildPolicy,
 			&latestVersion,
 			&publishedAt,
 			&lastReleasedAt,
@@ -291,7 +382,11 @@ func Query(whereClause, sortBy, limit, offset string, args ...interface{}) (pkgR
 			ID:               id,
 			Name:             name,
 			OwnerUsername:    ownerUsername,
+			Status:           status,
 			Downloads:        downloads,
+			DependentsCount:  dependentsCount,
+			SemverPolicy:     semverPolicy,
+			BuildPolicy:      buildPolicy,
 			LatestVersion:    latestVersion,
 			PublishedAt:      publishedAt,
 			LastReleasedAt:   lastReleasedAt,
@@ -345,13 +440,17 @@ func Versions(packageName string) (vHistory *VersionHistory, err error) {
 	*
 	FROM
 	(SELECT
-	package_versions.id, package_versions.version, package_versions.released_at, packages.id AS package_id, packages.name AS package_name 
+	package_versions.id, package_versions.version, package_versions.status, package_versions.sha256, package_versions.sha512, package_versions.build_status, package_changelog.excerpt AS notes_excerpt, package_versions.released_at, packages.id AS package_id, packages.name AS package_name 
 	FROM
 	packages
 	INNER JOIN
 	package_versions
 	ON
-	packages.id = package_versions.package_id) as package_versions
+	packages.id = package_versions.package_id
+	LEFT JOIN
+	package_changelog
+	ON
+	package_changelog.package_id = package_versions.package_id AND package_changelog.version = package_versions.version) as package_versions
 	`
 
 	sqlSt = fmt.Sprintf("%s WHERE package_name = ? ORDER BY released_at ASC", sqlSt)
@@ -371,6 +470,11 @@ func Versions(packageName string) (vHistory *VersionHistory, err error) {
 	var (
 		id            uint64
 		version       string
+		status        string
+		sha256        sql.NullString
+		sha512        sql.NullString
+		buildStatus   sql.NullString
+		notesExcerpt  sql.NullString
 		releasedAt    time.Time
 		packageID     uint64
 		packageNameDb string
@@ -380,6 +484,11 @@ func Versions(packageName string) (vHistory *VersionHistory, err error) {
 		err := rows.Scan(
 			&id,
 			&version,
+			&status,
+			&sha256,
+			&sha512,
+			&buildStatus,
+			&notesExcerpt,
 			&releasedAt,
 			&packageID,
 			&packageNameDb,
@@ -393,8 +502,15 @@ func Versions(packageName string) (vHistory *VersionHistory, err error) {
 		vHistory.Name = packageNameDb
 
 		sv := &SingleVersion{
-			Version:    version,
-			ReleasedAT: releasedAt,
+			PackageID:    packageID,
+			PackageName:  packageNameDb,
+			Version:      version,
+			Status:       status,
+			SHA256:       sha256.String,
+			SHA512:       sha512.String,
+			BuildStatus:  buildStatus.String,
+			NotesExcerpt: notesExcerpt.String,
+			ReleasedAT:   releasedAt,
 		}
 		versions = append(versions, sv)
 	}
@@ -413,19 +529,95 @@ func Versions(packageName string) (vHistory *VersionHistory, err error) {
 	return vHistory, nil
 }
 
+// Version returns a single version of a package, or nil if the package or the version does not exist.
+func Version(packageName, inpVersion string) (sv *SingleVersion, err error) {
+	vh, err := Versions(packageName)
+	if err != nil {
+		return
+	}
+
+	if vh == nil {
+		return nil, nil
+	}
+
+	for _, v := range *vh.Versions {
+		if ok, err := helper.IsSameVersion(v.Version, inpVersion); err != nil {
+			return nil, err
+		} else if ok {
+			return v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ArchiveDigests returns the recorded archive digests of all the versions which are stored.
+// If instanceID is not empty, the versions whose archives were stored by another instance
+// are left out, the versions recorded before the instances were tracked are kept.
+func ArchiveDigests(instanceID string) (digests []*VersionDigest, err error) {
+	sqlSt := `
+	SELECT packages.name, package_versions.version, package_versions.sha256, package_versions.sha512
+	FROM package_versions
+	INNER JOIN packages
+	ON packages.id = package_versions.package_id
+	WHERE package_versions.sha256 IS NOT NULL and package_versions.status IN (?, ?) and (? = '' or NOT EXISTS (
+		SELECT package_outbox.id
+		FROM package_outbox
+		WHERE package_outbox.package_id = package_versions.package_id and package_outbox.version = package_versions.version and package_outbox.operation = ? and package_outbox.instance_id <> ?
+	))
+	ORDER BY packages.name ASC, package_versions.id ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, constants.VersionStatusStored, constants.VersionStatusCommitted, instanceID, constants.OutboxOperationRegister, instanceID)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		vd := &VersionDigest{}
+		err = rows.Scan(&vd.PackageName, &vd.Version, &vd.SHA256, &vd.SHA512)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the archive digest query result")
+			return nil, err
+		}
+		digests = append(digests, vd)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the archive digest query result")
+		return nil, err
+	}
+
+	return digests, nil
+}
+
 // DeletePackage deletes a specific package by removing its data from
-// database and from vcs registry.
-func DeletePackage(packageName string) (err error) {
+// database and from vcs registry. The package is marked as deleting and the
+// deletion is recorded to the package outbox, so that it is completed later by
+// the reconciler if the vcs registry can not be reached at the moment.
+// It returns the resulting status of the deletion.
+func DeletePackage(packageName string) (status string, err error) {
+	unlock, err := lockPackage(packageName)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
 	sqlSt := `
-	SELECT id
+	SELECT id, status
 	FROM packages
 	WHERE name = ? ORDER BY id ASC LIMIT 1
 	`
 
 	dbConn := database.Conn()
 
-	var packageID uint64
-	err = dbConn.QueryRow(sqlSt, packageName).Scan(&packageID)
+	var (
+		packageID uint64
+		pkgStatus string
+	)
+	err = dbConn.QueryRow(sqlSt, packageName).Scan(&packageID, &pkgStatus)
 	if err != nil {
 		switch {
 		case err == sql.ErrNoRows:
@@ -436,6 +628,11 @@ func DeletePackage(packageName string) (err error) {
 		return
 	}
 
+	if pkgStatus == constants.PackageStatusDeleting {
+		err = ErrPackageDeleting
+		return
+	}
+
 	tx, err := dbConn.Begin()
 	if err != nil {
 		err = errors.Wrap(err, "Failed to begin a transaction")
@@ -443,90 +640,166 @@ func DeletePackage(packageName string) (err error) {
 	}
 
 	st := `
-	DELETE FROM packages
+	UPDATE packages
+	SET status = ?
 	WHERE id = ?
 	`
-	_, err = tx.Exec(st, packageID)
+	_, err = tx.Exec(st, constants.PackageStatusDeleting, packageID)
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from packages table")
+		err = errors.Wrap(err, "Failed to update package status to packages table")
 		return
 	}
 
-	st = `
-	DELETE FROM package_versions
-	WHERE package_id = ?
-	`
-	_, err = tx.Exec(st, packageID)
+	// The unfinished releases must not reach the vcs registry after the delete.
+	err = cancelRegisterEntries(tx, packageID)
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from package_versions table")
 		return
 	}
 
-	st = `
-	DELETE FROM package_tags
-	WHERE package_id = ?
-	`
-	_, err = tx.Exec(st, packageID)
+	entryID, err := insertOutboxEntry(tx, packageID, packageName, "", constants.OutboxOperationDelete, "")
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from package_tags table")
 		return
 	}
 
-	st = `
-	DELETE FROM package_readme
-	WHERE package_id = ?
-	`
-	_, err = tx.Exec(st, packageID)
+	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from package_readme table")
+		err = errors.Wrap(err, "Failed to commit changes of deleting package")
 		return
 	}
 
-	st = `
-	DELETE FROM package_downloads
-	WHERE package_id = ?
-	`
-	_, err = tx.Exec(st, packageID)
+	status, err = ProcessOutboxEntry(entryID)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from package_downloads table")
+		// The deletion is durably recorded, so the reconciler completes it later.
+		log.Error("Error %s", err)
+		return constants.VersionStatusPending, nil
+	}
+
+	return status, nil
+}
+
+// UpdateInfo updates the settings of a package and returns the updated package.
+func UpdateInfo(packageID uint64, data *MutationData) (pkg *QueryRow, err error) {
+	sqlSts := []string{}
+	placeholderVals := []interface{}{}
+
+	if data.SemverPolicy != nil {
+		sqlSts = append(sqlSts, "semver_policy = ?")
+		placeholderVals = append(placeholderVals, *data.SemverPolicy)
+	}
+
+	if data.BuildPolicy != nil {
+		sqlSts = append(sqlSts, "build_policy = ?")
+		placeholderVals = append(placeholderVals, *data.BuildPolicy)
+	}
+
+	if len(sqlSts) > 0 {
+		st := fmt.Sprintf(`
+		UPDATE packages
+		SET %s
+		WHERE id = ?`, strings.Join(sqlSts, ", "))
+		placeholderVals = append(placeholderVals, packageID)
+
+		dbConn := database.Conn()
+		_, err = dbConn.Exec(st, placeholderVals...)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to update packages table")
+			return
+		}
+	}
+
+	pkgRows, err := Query("id = ?", "id ASC", "1", "", packageID)
+	if err != nil || len(pkgRows) < 1 {
+		err = errors.Wrap(err, "Failed to read updated package data")
 		return
 	}
 
-	err = vcs.DeletePackage(packageName)
+	return pkgRows[0], nil
+}
+
+// SyncPackageOwner re-syncs the owner info of all the packages of an user
+// to the vcs registry e.g. after the user changes username. The syncs are
+// recorded in the package outbox, so that the failed ones are retried by the
+// reconciler, and the error is only returned if they could not be recorded.
+func SyncPackageOwner(ownerInfo *user.QueryRow) (err error) {
+	pkgRows, err := Query("owner_username = ?", "id ASC", "", "", ownerInfo.Username)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete package data from vcs registry")
+		err = errors.Wrap(err, "Failed to query packages of the owner")
+		return
+	}
+
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
 		return
 	}
 
+	entryIDs := []uint64{}
+	for _, pr := range pkgRows {
+		entryID, err := insertOutboxEntry(tx, pr.ID, pr.Name, "", constants.OutboxOperationSyncOwner, "")
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		entryIDs = append(entryIDs, entryID)
+	}
+
 	err = tx.Commit()
-	// Possibility is very low.
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to commit changes of deleting package")
+		err = errors.Wrap(err, "Failed to commit changes of syncing package owner")
 		return
 	}
 
+	for _, entryID := range entryIDs {
+		_, err := ProcessOutboxEntry(entryID)
+		if err != nil {
+			// The sync is durably recorded, so the reconciler completes it later.
+			log.Error("Error %s", err)
+		}
+	}
+
 	return nil
 }
 
 // SanitizePackageMeta sanitizes the input metadata.
 func SanitizePackageMeta(meta *types.PackageMetaData) (err error) {
+	errs := sanitizePackageMeta(meta)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+// sanitizePackageMeta normalizes the metadata and returns all the validation errors.
+func sanitizePackageMeta(meta *types.PackageMetaData) (errs []error) {
 	meta.Name = strings.TrimSpace(meta.Name)
-	err = helper.ValidatePackageName(meta.Name)
+	err := helper.ValidatePackageName(meta.Name)
 	if err != nil {
-		return
+		errs = append(errs, err)
 	}
 
 	meta.Version = strings.TrimSpace(meta.Version)
-	meta.Version, err = helper.SanitizePackageVersion(meta.Version)
+	sVersion, err := helper.SanitizePackageVersion(meta.Version)
 	if err != nil {
-		return
+		errs = append(errs, err)
+	} else {
+		meta.Version = sVersion
+	}
+
+	meta.License = strings.TrimSpace(meta.License)
+	if !str.IsEmpty(meta.License) {
+		license, err := spdx.Normalize(meta.License)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			meta.License = license
+		}
 	}
 
 	if meta.Tags == nil {
@@ -563,11 +836,90 @@ func SanitizePackageMeta(meta *types.PackageMetaData) (err error) {
 	}
 	meta.Os = sOs
 
-	return
+	if meta.Commands == nil {
+		meta.Commands = map[string]string{}
+	}
+	if len(meta.Commands) > constants.PackageCommandsMaxCount {
+		errs = append(errs, errors.Errorf("Package must have maximum %d commands", constants.PackageCommandsMaxCount))
+	}
+	sCommands := map[string]string{}
+	for _, name := range sortedStringKeys(meta.Commands) {
+		cmd := strings.TrimSpace(meta.Commands[name])
+		name = strings.TrimSpace(name)
+		err := helper.ValidatePackageCommand(name, cmd)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sCommands[name] = cmd
+	}
+	meta.Commands = sCommands
+
+	if meta.Dependencies == nil {
+		meta.Dependencies = map[string]string{}
+	}
+	if len(meta.Dependencies) > constants.PackageDependenciesMaxCount {
+		errs = append(errs, errors.Errorf("Package must have maximum %d dependencies", constants.PackageDependenciesMaxCount))
+	}
+	sDependencies := map[string]string{}
+	for _, name := range sortedStringKeys(meta.Dependencies) {
+		constraint := strings.TrimSpace(meta.Dependencies[name])
+		name = strings.TrimSpace(name)
+		if str.IsEmpty(constraint) {
+			constraint = "*"
+		}
+		err := helper.ValidatePackageDependency(name, constraint)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sDependencies[name] = constraint
+	}
+	meta.Dependencies = sDependencies
+
+	return errs
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // InsertNew inserts a new package to the database and registers to the vcs registry.
-func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.QueryRow) (pkg *QueryRow, err error) {
+// The package data is kept on local storage and the registration is recorded to the
+// package outbox in the same transaction, so the vcs registry is never called while
+// the transaction is open. It returns the resulting status of the published version.
+func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ins *archive.Inspection, build *BuildResult, ownerInfo *user.QueryRow) (pkg *QueryRow, status string, err error) {
+	readmeFileName, readmeContent := packageReadme(meta.Name, ins)
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to encode package metadata")
+		return
+	}
+
+	_, err = data.Seek(0, 0)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to seek the data reader to starting position")
+		return
+	}
+
+	err = storage.SaveArchive(meta.Name, meta.Version, data)
+	if err != nil {
+		return
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			storage.RemoveArchive(meta.Name, meta.Version)
+		}
+	}()
+
 	dbConn := database.Conn()
 	tx, err := dbConn.Begin()
 	if err != nil {
@@ -577,14 +929,17 @@ func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.
 
 	st := `
 	INSERT INTO packages
-	(name, owner_id, latest_version, description, license, homepage_url, repository_url, documentation_url, bugs_url, engines_go, os)
+	(name, owner_id, status, semver_policy, build_policy, latest_version, description, license, homepage_url, repository_url, documentation_url, bugs_url, engines_go, os)
 	VALUES 
-	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	r, err := tx.Exec(
 		st,
 		meta.Name,
 		ownerInfo.ID,
+		constants.PackageStatusPending,
+		constants.DefaultSemverPolicy,
+		constants.DefaultBuildPolicy,
 		meta.Version,
 		meta.Description,
 		meta.License,
@@ -601,25 +956,13 @@ func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.
 		return
 	}
 
-	packageID, err := r.LastInsertId()
+	lastInsertID, err := r.LastInsertId()
 	if err != nil {
 		tx.Rollback()
 		err = errors.Wrap(err, "Failed to retrive the last insert ID")
 		return
 	}
-
-	st = `
-	INSERT INTO package_versions
-	(version, package_id)
-	VALUES 
-	(?, ?)
-	`
-	_, err = tx.Exec(st, meta.Version, packageID)
-	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to insert package data to package_versions table")
-		return
-	}
+	packageID := uint64(lastInsertID)
 
 	st = `
 	INSERT INTO package_tags
@@ -644,61 +987,76 @@ func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.
 	}
 	prepSt.Close()
 
-	var readmeBuff bytes.Buffer
-	ok, idx, err := fs.ReadEntryTarGz(data, &readmeBuff, constants.ReadmeFileNames)
+	entryID, err := insertPendingVersion(tx, packageID, meta, metaJSON, ins, build, readmeFileName, readmeContent)
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrapf(err, "Failed to retrive README file")
 		return
 	}
 
-	var readmeFileName string
-	var readmeContent []byte
-	if ok {
-		readmeFileName = constants.ReadmeFileNames[idx]
-		readmeContent = readmeBuff.Bytes()
-		if len(readmeContent) == 0 {
-			readmeContent = defaultPackageReadme(meta.Name)
-		}
-	} else {
-		readmeFileName = constants.DefaultReadmeFileName
-		readmeContent = defaultPackageReadme(meta.Name)
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes")
+		return
 	}
+	committed = true
 
-	st = `
-	INSERT INTO package_readme
-	(package_id, version, name, file_size, content)
-	VALUES 
-	(?, ?, ?, ?, ?)
-	`
-	_, err = tx.Exec(st, packageID, meta.Version, readmeFileName, len(readmeContent), readmeContent)
+	status, err = ProcessOutboxEntry(entryID)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to insert package data to package_readme table")
+		// The version is durably recorded, so the reconciler completes it later.
+		log.Error("Error %s", err)
+		status, err = constants.VersionStatusPending, nil
+	}
+
+	pkgRows, err := Query("id = ?", "id ASC", "1", "", packageID)
+	if err != nil || len(pkgRows) == 0 {
+		err = errors.Wrap(err, "Failed to read updated package data")
+		return
+	}
+
+	return pkgRows[0], status, nil
+}
+
+// MakeNewRelease creates a new release/version to the database and registers that version to the vcs registry.
+// The package data becomes the latest release only after the vcs registry has stored it.
+// It returns the resulting status of the published version.
+func MakeNewRelease(packageID uint64, meta *types.PackageMetaData, data io.ReadSeeker, ins *archive.Inspection, build *BuildResult) (pkg *QueryRow, status string, err error) {
+	readmeFileName, readmeContent := packageReadme(meta.Name, ins)
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to encode package metadata")
 		return
 	}
 
 	_, err = data.Seek(0, 0)
 	if err != nil {
-		tx.Rollback()
 		err = errors.Wrapf(err, "Failed to seek the data reader to starting position")
 		return
 	}
 
-	vcsMeta := &vcs.PackageMeta{
-		Type:    vcs.PackageTypePublic,
-		Name:    meta.Name,
-		Version: meta.Version,
-		Owner: vcs.PackageOwner{
-			Name:        ownerInfo.Name,
-			PublicEmail: misc.TerOpt(ownerInfo.IsPublicEmail, ownerInfo.Email, "").(string),
-			Username:    ownerInfo.Username,
-		},
+	err = storage.SaveArchive(meta.Name, meta.Version, data)
+	if err != nil {
+		return
 	}
-	err = vcs.RegisterPackage(vcsMeta, data)
+
+	committed := false
+	defer func() {
+		if !committed {
+			storage.RemoveArchive(meta.Name, meta.Version)
+		}
+	}()
+
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	entryID, err := insertPendingVersion(tx, packageID, meta, metaJSON, ins, build, readmeFileName, readmeContent)
 	if err != nil {
 		tx.Rollback()
-		err = errors.Wrap(err, "Failed to register package to vcs registry")
 		return
 	}
 
@@ -708,6 +1066,14 @@ func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.
 		err = errors.Wrap(err, "Failed to commit changes")
 		return
 	}
+	committed = true
+
+	status, err = ProcessOutboxEntry(entryID)
+	if err != nil {
+		// The version is durably recorded, so the reconciler completes it later.
+		log.Error("Error %s", err)
+		status, err = constants.VersionStatusPending, nil
+	}
 
 	pkgRows, err := Query("id = ?", "id ASC", "1", "", packageID)
 	if err != nil || len(pkgRows) == 0 {
@@ -715,161 +1081,131 @@ func InsertNew(meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.
 		return
 	}
 
-	return pkgRows[0], nil
+	return pkgRows[0], status, nil
 }
 
-// MakeNewRelease creates a new release/version to the database and registers that version to the vcs registry.
-func MakeNewRelease(packageID uint64, meta *types.PackageMetaData, data io.ReadSeeker, ownerInfo *user.QueryRow) (pkg *QueryRow, err error) {
-	dbConn := database.Conn()
-	tx, err := dbConn.Begin()
+// insertPendingVersion inserts a pending version along with its archive digests,
+// README, commands, dependencies, imports, go.mod, exported symbols and docs, and records the registration of the version to the package outbox.
+func insertPendingVersion(tx *sql.Tx, packageID uint64, meta *types.PackageMetaData, metaJSON []byte, ins *archive.Inspection, build *BuildResult, readmeFileName string, readmeContent []byte) (entryID uint64, err error) {
+	buildStatus, buildDiagnostics, err := buildColumns(build)
 	if err != nil {
-		err = errors.Wrap(err, "Failed to begin a transaction")
 		return
 	}
 
 	st := `
-	UPDATE packages
-	SET latest_version = ?, description = ?, license = ?, homepage_url = ?, repository_url = ?, documentation_url = ?, bugs_url = ?, engines_go = ?, os = ?
-	WHERE id = ?
+	INSERT INTO package_versions
+	(version, package_id, status, meta, archive_size, sha256, sha512, build_status, build_diagnostics, api_extracted)
+	VALUES
+	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err = tx.Exec(
-		st,
-		meta.Version,
-		meta.Description,
-		meta.License,
-		meta.HomepageURL,
-		meta.RepositoryURL,
-		meta.DocumentationURL,
-		meta.BugsURL,
-		meta.Engines.Go,
-		strings.Join(meta.Os, ", "),
-		packageID,
-	)
+	_, err = tx.Exec(st, meta.Version, packageID, constants.VersionStatusPending, metaJSON, ins.CompressedSize, ins.SHA256, ins.SHA512, buildStatus, buildDiagnostics, apiComplete(ins))
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to update package data to packages table")
+		err = errors.Wrap(err, "Failed to insert package data to package_versions table")
 		return
 	}
 
 	st = `
-	INSERT INTO package_versions
-	(version, package_id)
-	VALUES
-	(?, ?)
+	INSERT INTO package_readme
+	(package_id, version, name, file_size, content)
+	VALUES 
+	(?, ?, ?, ?, ?)
 	`
-	_, err = tx.Exec(st, meta.Version, packageID)
+	_, err = tx.Exec(st, packageID, meta.Version, readmeFileName, len(readmeContent), readmeContent)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to insert package data to package_versions table")
+		err = errors.Wrap(err, "Failed to insert package data to package_readme table")
 		return
 	}
 
 	st = `
-	DELETE FROM package_tags
-	WHERE package_id = ?
+	INSERT INTO package_commands
+	(package_id, version, name, command)
+	VALUES
+	(?, ?, ?, ?)
 	`
-	_, err = tx.Exec(st, packageID)
-	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to delete existing tags from package_tags table")
-		return
+	for name, cmd := range meta.Commands {
+		_, err = tx.Exec(st, packageID, meta.Version, name, cmd)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert commands to package_commands table")
+			return
+		}
 	}
 
 	st = `
-	INSERT INTO package_tags
-	(package_id, tag)
-	VALUES 
-	(?, ?)
+	INSERT INTO package_dependencies
+	(package_id, version, name, version_constraint, kind)
+	VALUES
+	(?, ?, ?, ?, ?)
 	`
-	prepSt, err := tx.Prepare(st)
-	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to create prepared statement")
-		return
+	for name, constraint := range meta.Dependencies {
+		_, err = tx.Exec(st, packageID, meta.Version, name, constraint, helper.DependencyKind(name))
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert dependencies to package_dependencies table")
+			return
+		}
 	}
 
-	for _, v := range meta.Tags {
-		_, err = prepSt.Exec(packageID, v)
+	st = `
+	INSERT INTO package_imports
+	(package_id, version, import_path, kind)
+	VALUES
+	(?, ?, ?, ?)
+	`
+	for _, imp := range classifyImports(ins.Imports) {
+		_, err = tx.Exec(st, packageID, meta.Version, imp.Path, imp.Kind)
 		if err != nil {
-			tx.Rollback()
-			err = errors.Wrap(err, "Failed to insert tags to package_tags table")
+			err = errors.Wrap(err, "Failed to insert imports to package_imports table")
 			return
 		}
 	}
-	prepSt.Close()
 
-	var readmeBuff bytes.Buffer
-	ok, idx, err := fs.ReadEntryTarGz(data, &readmeBuff, constants.ReadmeFileNames)
+	err = insertFiles(tx, packageID, meta.Version, ins.Files)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrapf(err, "Failed to retrive README file")
 		return
 	}
 
-	var readmeFileName string
-	var readmeContent []byte
-	if ok {
-		readmeFileName = constants.ReadmeFileNames[idx]
-		readmeContent = readmeBuff.Bytes()
-		if len(readmeContent) == 0 {
-			readmeContent = defaultPackageReadme(meta.Name)
-		}
-	} else {
-		readmeFileName = constants.DefaultReadmeFileName
-		readmeContent = defaultPackageReadme(meta.Name)
+	err = insertChangelog(tx, packageID, meta.Version, ins)
+	if err != nil {
+		return
 	}
 
-	st = `
-	INSERT INTO package_readme
-	(package_id, version, name, file_size, content)
-	VALUES 
-	(?, ?, ?, ?, ?)
-	`
-	_, err = tx.Exec(st, packageID, meta.Version, readmeFileName, len(readmeContent), readmeContent)
+	gm, err := parseGoMod(ins)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to insert package data to package_readme table")
+		err = errors.Wrap(err, "Failed to parse go.mod file")
 		return
 	}
 
-	_, err = data.Seek(0, 0)
+	err = insertGoMod(tx, packageID, meta.Version, gm)
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrapf(err, "Failed to seek the data reader to starting position")
 		return
 	}
 
-	vcsMeta := &vcs.PackageMeta{
-		Type:    vcs.PackageTypePublic,
-		Name:    meta.Name,
-		Version: meta.Version,
-		Owner: vcs.PackageOwner{
-			Name:        ownerInfo.Name,
-			PublicEmail: misc.TerOpt(ownerInfo.IsPublicEmail, ownerInfo.Email, "").(string),
-			Username:    ownerInfo.Username,
-		},
-	}
-	err = vcs.RegisterPackage(vcsMeta, data)
+	srcPkgs := sourcePackages(ins, modulePath(meta, gm))
+	err = insertSymbols(tx, packageID, meta.Version, extractSymbols(ins.FileSet, srcPkgs))
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to register package's new version to vcs registry")
 		return
 	}
 
-	err = tx.Commit()
+	err = insertDocs(tx, packageID, meta.Version, extractDocs(ins.FileSet, srcPkgs))
 	if err != nil {
-		tx.Rollback()
-		err = errors.Wrap(err, "Failed to commit changes")
 		return
 	}
 
-	pkgRows, err := Query("id = ?", "id ASC", "1", "", packageID)
-	if err != nil || len(pkgRows) == 0 {
-		err = errors.Wrap(err, "Failed to read updated package data")
-		return
+	return insertOutboxEntry(tx, packageID, meta.Name, meta.Version, constants.OutboxOperationRegister, ins.SHA256)
+}
+
+// packageReadme returns the README file captured from the package data, or creates a default one.
+func packageReadme(pkgName string, ins *archive.Inspection) (readmeFileName string, readmeContent []byte) {
+	if str.IsEmpty(ins.ReadmeFileName) {
+		return constants.DefaultReadmeFileName, defaultPackageReadme(pkgName)
 	}
 
-	return pkgRows[0], nil
+	readmeFileName = ins.ReadmeFileName
+	readmeContent = ins.ReadmeContent
+	if len(readmeContent) == 0 {
+		readmeContent = defaultPackageReadme(pkgName)
+	}
+
+	return
 }
 
 // VersionExists checks whether the input version exists or not.
@@ -895,6 +1231,40 @@ func defaultPackageReadme(pkgName string) (content []byte) {
 	return
 }
 
+// Commands returns the commands of a package version.
+func Commands(packageID uint64, version string) (commands map[string]string, err error) {
+	sqlSt := `
+	SELECT name, command
+	FROM package_commands
+	WHERE package_id = ? and version = ?
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands = map[string]string{}
+	for rows.Next() {
+		var name, command string
+		err = rows.Scan(&name, &command)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package commands query result")
+			return nil, err
+		}
+		commands[name] = command
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package commands query result")
+		return nil, err
+	}
+
+	return commands, nil
+}
+
 // Readme returns the README content of a package.
 func Readme(packageID uint64, version string) (content *ReadmeData, err error) {
 	sqlSt := `
@@ -928,6 +1298,7 @@ func Readme(packageID uint64, version string) (content *ReadmeData, err error) {
 		Version: version,
 		Size:    size,
 		Content: base64.StdEncoding.EncodeToString(readmeContent),
+		Raw:     readmeContent,
 	}
 
 	return
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/user"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/config"
+	"gopx.io/gopx-api/pkg/controller/database"
+	"gopx.io/gopx-common/str"
+)
+
+// PublishError represents a publish failure caused by the uploaded package data
+// or by the permissions of the publisher.
+// The offending archive entries are listed in Entries, if any.
+type PublishError struct {
+	StatusCode int
+	Message    string
+	Errors     []string
+	Warnings   []string
+	Entries    []*types.ArchiveEntryError
+}
+
+func (pe *PublishError) Error() string {
+	return pe.Message
+}
+
+// PublishResult holds the outcome of a successful publish.
+type PublishResult struct {
+	Package  *QueryRow
+	Version  string
+	Status   string
+	Warnings []string
+}
+
+// MaxDataSize returns the maximum allowed size of the package data uploaded by the owner.
+func MaxDataSize(ownerInfo *user.QueryRow) int64 {
+	if size, ok := config.Upload.UserMaxPackageSize[ownerInfo.Username]; ok && size > 0 {
+		return size
+	}
+
+	if !str.IsEmpty(ownerInfo.Organization) {
+		if size, ok := config.Upload.OrganizationMaxPackageSize[ownerInfo.Organization]; ok && size > 0 {
+			return size
+		}
+	}
+
+	if config.Upload.MaxPackageSize > 0 {
+		return config.Upload.MaxPackageSize
+	}
+
+	return constants.PackageDataMaxSize
+}
+
+// Publish validates the uploaded package data and publishes it as a new package,
+// or as a new release of an existing package of the owner.
+// In strict mode, the warnings about the metadata fields fail the publish.
+// The progress function, if not nil, is called whenever the publish enters a new stage,
+// along with the validated metadata once it is known.
+func Publish(data io.ReadSeeker, ownerInfo *user.QueryRow, strict bool, progress func(stage string, meta *types.PackageMetaData)) (result *PublishResult, err error) {
+	if progress == nil {
+		progress = func(stage string, meta *types.PackageMetaData) {}
+	}
+
+	progress(constants.PublishStageValidating, nil)
+
+	v, err := validateData(data, strict)
+	if err != nil {
+		return
+	}
+
+	if !v.Valid() {
+		err = v.publishError()
+		return
+	}
+
+	err = v.checkDependencies()
+	if err != nil {
+		return
+	}
+
+	if !v.Valid() {
+		err = v.publishError()
+		return
+	}
+
+	meta := v.Meta
+
+	// The existence checks and the inserts below must not interleave with another
+	// publish or delete of the same package.
+	unlock, err := lockPackage(meta.Name)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = v.checkPublisher(ownerInfo)
+	if err != nil {
+		return
+	}
+
+	err = v.checkSemver()
+	if err != nil {
+		return
+	}
+
+	err = v.checkBuild()
+	if err != nil {
+		return
+	}
+
+	if !v.Valid() {
+		err = v.publishError()
+		return
+	}
+
+	progress(constants.PublishStagePublishing, meta)
+
+	if v.Package == nil {
+		iPkg, status, err := InsertNew(meta, data, v.Inspection, v.Build, ownerInfo)
+		if err != nil {
+			// Another instance of the service published the same name in the meantime.
+			if database.IsDuplicateEntry(err) {
+				err = &PublishError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("Package %s already exists", meta.Name)}
+			}
+			return nil, err
+		}
+
+		return &PublishResult{Package: iPkg, Version: meta.Version, Status: status, Warnings: v.Warnings}, nil
+	}
+
+	uPkg, status, err := MakeNewRelease(v.Package.ID, meta, data, v.Inspection, v.Build)
+	if err != nil {
+		if database.IsDuplicateEntry(err) {
+			err = &PublishError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("Package version %s already exists", meta.Version)}
+		}
+		return
+	}
+
+	return &PublishResult{Package: uPkg, Version: meta.Version, Status: status, Warnings: v.Warnings}, nil
+}
@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/controller/readme"
+	"gopx.io/gopx-api/pkg/controller/database"
+)
+
+// ReadmeHTML returns the README of a package version rendered as sanitized HTML, with the
+// relative links pointing to filesURL. The versions are immutable, so the rendered HTML
+// is cached in package_readme table on the first request.
+func ReadmeHTML(packageID uint64, version, filesURL string) (rendered []byte, err error) {
+	sqlSt := `
+	SELECT content, html
+	FROM package_readme
+	WHERE package_id = ? and version = ?
+	ORDER BY id ASC
+	LIMIT 1
+	`
+	var (
+		content []byte
+		cached  []byte
+	)
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, packageID, version).Scan(&content, &cached)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			err = errors.Wrapf(err, "Package not found")
+			return
+		default:
+			err = errors.Wrapf(err, "Failed to read README data from package_readme table")
+			return
+		}
+	}
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	rendered, err = readme.RenderHTML(content, filesURL)
+	if err != nil {
+		return
+	}
+
+	st := `
+	UPDATE package_readme
+	SET html = ?
+	WHERE package_id = ? and version = ?
+	`
+	_, err = dbConn.Exec(st, rendered, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to cache rendered README to package_readme table")
+		return
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,265 @@
+package pkg
+
+import (
+	"bytes"
+	"database/sql"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-api/pkg/controller/database"
+)
+
+// Symbol holds an exported symbol of the Go sources of a package version.
+// Receiver is the type name of a method, and empty for the other kinds.
+type Symbol struct {
+	ImportPath string
+	Kind       string
+	Receiver   string
+	Name       string
+	Signature  string
+	Doc        string
+}
+
+// modulePath returns the import path prefix of the Go sources of a package version,
+// which is the module path of go.mod if there is one.
+func modulePath(meta *types.PackageMetaData, gm *GoModule) string {
+	if gm != nil {
+		return gm.Path
+	}
+
+	return constants.PackageImportPathPrefix + meta.Name
+}
+
+// docPlatforms holds the platforms whose source files are documented, a file excluded
+// by its build constraints on all of them e.g. "//go:build ignore" is not documented.
+var docPlatforms = []struct{ goos, goarch string }{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+	{"windows", "386"},
+	{"freebsd", "amd64"},
+	{"js", "wasm"},
+}
+
+// docFiles returns the source files of a directory which match the build constraints
+// for one of the documented platforms.
+func docFiles(ins *archive.Inspection, dir string) (files []*ast.File) {
+	for _, f := range ins.Sources[dir] {
+		for _, p := range docPlatforms {
+			ctx := build.Default
+			ctx.GOOS, ctx.GOARCH = p.goos, p.goarch
+			if matchFile(ins, ctx, dir, f) {
+				files = append(files, f)
+				break
+			}
+		}
+	}
+
+	return files
+}
+
+// sourcePackages computes the documentation of the non-main packages of the parsed
+// Go sources, the test files only contribute the examples. The files excluded by
+// their build constraints on every documented platform are left out, and then the
+// directories having files of different packages are skipped, the go tool could not
+// build those either.
+func sourcePackages(ins *archive.Inspection, modPath string) (pkgs []*doc.Package) {
+	dirs := make([]string, 0, len(ins.Sources))
+	for dir := range ins.Sources {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		files := docFiles(ins, dir)
+
+		var pkgName string
+		mixed := false
+		for _, f := range files {
+			if isTestFile(ins, f) {
+				continue
+			}
+			if pkgName == "" {
+				pkgName = f.Name.Name
+			} else if f.Name.Name != pkgName {
+				mixed = true
+			}
+		}
+
+		if mixed || pkgName == "" || pkgName == "main" {
+			continue
+		}
+
+		// The test files of the other packages do not contribute any examples.
+		pkgFiles := []*ast.File{}
+		for _, f := range files {
+			if f.Name.Name == pkgName || f.Name.Name == pkgName+"_test" {
+				pkgFiles = append(pkgFiles, f)
+			}
+		}
+
+		// The ASTs are shared with the other consumers of the inspection, so they must not be edited.
+		p, err := doc.NewFromFiles(ins.FileSet, pkgFiles, path.Join(modPath, dir), doc.PreserveAST)
+		if err != nil {
+			continue
+		}
+
+		pkgs = append(pkgs, p)
+	}
+
+	return pkgs
+}
+
+// extractSymbols extracts the exported functions, types, methods, constants and
+// variables of the Go sources along with their signatures and doc comments.
+func extractSymbols(fset *token.FileSet, pkgs []*doc.Package) (symbols []*Symbol) {
+	symbols = []*Symbol{}
+
+	for _, p := range pkgs {
+		add := func(kind, receiver, name, signature, docText string) {
+			symbols = append(symbols, &Symbol{
+				ImportPath: p.ImportPath,
+				Kind:       kind,
+				Receiver:   receiver,
+				Name:       name,
+				Signature:  signature,
+				Doc:        docText,
+			})
+		}
+
+		addValues := func(values []*doc.Value) {
+			for _, v := range values {
+				kind := constants.SymbolKindConst
+				if v.Decl.Tok == token.VAR {
+					kind = constants.SymbolKindVar
+				}
+
+				for _, spec := range v.Decl.Specs {
+					vs := spec.(*ast.ValueSpec)
+					docText := v.Doc
+					if vs.Doc != nil {
+						docText = vs.Doc.Text()
+					}
+
+					signature := printNode(fset, &ast.GenDecl{
+						Tok:   v.Decl.Tok,
+						Specs: []ast.Spec{&ast.ValueSpec{Names: vs.Names, Type: vs.Type, Values: vs.Values}},
+					})
+					for _, name := range vs.Names {
+						if name.IsExported() {
+							add(kind, "", name.Name, signature, docText)
+						}
+					}
+				}
+			}
+		}
+
+		addFuncs := func(receiver string, funcs []*doc.Func) {
+			kind := constants.SymbolKindFunc
+			if receiver != "" {
+				kind = constants.SymbolKindMethod
+			}
+
+			for _, f := range funcs {
+				signature := printNode(fset, &ast.FuncDecl{Recv: f.Decl.Recv, Name: f.Decl.Name, Type: f.Decl.Type})
+				add(kind, receiver, f.Name, signature, f.Doc)
+			}
+		}
+
+		addValues(p.Consts)
+		addValues(p.Vars)
+		addFuncs("", p.Funcs)
+
+		for _, t := range p.Types {
+			ts := t.Decl.Specs[0].(*ast.TypeSpec)
+			signature := printNode(fset, &ast.GenDecl{
+				Tok:   token.TYPE,
+				Specs: []ast.Spec{&ast.TypeSpec{Name: ts.Name, TypeParams: ts.TypeParams, Assign: ts.Assign, Type: ts.Type}},
+			})
+			add(constants.SymbolKindType, "", t.Name, signature, t.Doc)
+
+			addValues(t.Consts)
+			addValues(t.Vars)
+			addFuncs("", t.Funcs)
+			addFuncs(t.Name, t.Methods)
+		}
+	}
+
+	return symbols
+}
+
+// printNode formats the AST node like gofmt does.
+func printNode(fset *token.FileSet, node interface{}) string {
+	var buff bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buff, fset, node); err != nil {
+		return ""
+	}
+
+	return buff.String()
+}
+
+// insertSymbols inserts the exported symbols of a pending version.
+func insertSymbols(tx *sql.Tx, packageID uint64, version string, symbols []*Symbol) (err error) {
+	st := `
+	INSERT INTO package_symbols
+	(package_id, version, import_path, kind, receiver, name, signature, doc)
+	VALUES
+	(?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, s := range symbols {
+		_, err = tx.Exec(st, packageID, version, s.ImportPath, s.Kind, s.Receiver, s.Name, s.Signature, s.Doc)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to insert symbols to package_symbols table")
+			return
+		}
+	}
+
+	return nil
+}
+
+// Symbols returns the exported symbols of a package version.
+func Symbols(packageID uint64, version string) (symbols []*Symbol, err error) {
+	sqlSt := `
+	SELECT import_path, kind, receiver, name, signature, doc
+	FROM package_symbols
+	WHERE package_id = ? and version = ?
+	ORDER BY import_path ASC, receiver ASC, name ASC
+	`
+	dbConn := database.Conn()
+	rows, err := dbConn.Query(sqlSt, packageID, version)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to execute query statement")
+		return nil, err
+	}
+	defer rows.Close()
+
+	symbols = []*Symbol{}
+	for rows.Next() {
+		s := &Symbol{}
+		err = rows.Scan(&s.ImportPath, &s.Kind, &s.Receiver, &s.Name, &s.Signature, &s.Doc)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to scan the package symbols query result")
+			return nil, err
+		}
+		symbols = append(symbols, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		err = errors.Wrap(err, "Failed to fetch the package symbols query result")
+		return nil, err
+	}
+
+	return symbols, nil
+}
@@ -0,0 +1,247 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/spdx"
+	"gopx.io/gopx-api/api/v1/controller/user"
+	"gopx.io/gopx-api/api/v1/types"
+	"gopx.io/gopx-common/str"
+)
+
+// Validation holds the outcome of validating an uploaded package.
+// StatusCode is the http status code of the first error, if any.
+type Validation struct {
+	StatusCode int
+	Errors     []string
+	Warnings   []string
+	Entries    []*types.ArchiveEntryError
+	Meta       *types.PackageMetaData
+	Inspection *archive.Inspection
+	GoMod      *GoModule
+	Build      *BuildResult
+	Package    *QueryRow
+}
+
+// Valid checks whether the package can be published.
+func (v *Validation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+func (v *Validation) fail(statusCode int, message string) {
+	if len(v.Errors) == 0 {
+		v.StatusCode = statusCode
+	}
+	v.Errors = append(v.Errors, message)
+}
+
+func (v *Validation) warn(message string) {
+	v.Warnings = append(v.Warnings, message)
+}
+
+// publishError converts the validation errors to a publish error.
+func (v *Validation) publishError() *PublishError {
+	return &PublishError{StatusCode: v.StatusCode, Message: v.Errors[0], Errors: v.Errors, Warnings: v.Warnings, Entries: v.Entries}
+}
+
+// Validate runs every check of the publish on the uploaded package data without
+// writing anything, and collects all the errors and warnings instead of stopping
+// at the first one. The normalized metadata is returned in Meta if it could be decoded.
+// In strict mode, the unknown fields and the values of unexpected types in the metadata
+// are errors instead of warnings.
+// A non-nil error means the validation itself failed.
+func Validate(data io.Reader, ownerInfo *user.QueryRow, strict bool) (v *Validation, err error) {
+	v, err = validateData(data, strict)
+	if err != nil {
+		return
+	}
+
+	// The name is invalid, it is already reported.
+	if v.Meta == nil || helper.ValidatePackageName(v.Meta.Name) != nil {
+		return v, nil
+	}
+
+	err = v.checkPublisher(ownerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.checkSemver()
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.checkDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.checkBuild()
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// validateData validates the package archive and its metadata, which does not
+// depend on the current state of the registry.
+func validateData(data io.Reader, strict bool) (v *Validation, err error) {
+	v = &Validation{Errors: []string{}, Warnings: []string{}}
+
+	ins, err := archive.Inspect(data, archive.ConfiguredLimits())
+	if err != nil {
+		v.fail(http.StatusBadRequest, "Invalid package data")
+		return v, nil
+	}
+	v.Inspection = ins
+
+	if len(ins.Invalid) > 0 {
+		v.Entries = ins.Invalid
+		v.fail(http.StatusBadRequest, "The package archive contains unsafe or oversized entries")
+	}
+
+	if str.IsEmpty(ins.MetaFileName) {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("The meta file %s not found in package contents", strings.Join(constants.PackageMetaFileNames, " or ")))
+		return v, nil
+	}
+
+	meta, decodeWarnings, err := decodePackageMeta(ins.MetaFileName, ins.MetaContent)
+	if err != nil {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("Problems parsing %s file", ins.MetaFileName))
+		return v, nil
+	}
+
+	for _, w := range decodeWarnings {
+		if strict {
+			v.fail(http.StatusBadRequest, w)
+		} else {
+			v.warn(w)
+		}
+	}
+
+	for _, err := range sanitizePackageMeta(meta) {
+		if err == constants.ErrInternalServer {
+			return nil, err
+		}
+		v.fail(http.StatusBadRequest, err.Error())
+	}
+	v.Meta = meta
+
+	if str.IsEmpty(meta.Description) {
+		v.warn("The package description is empty")
+	}
+	if str.IsEmpty(meta.License) {
+		v.warn("The package license is not specified")
+	}
+	for _, id := range spdx.Unknown(meta.License) {
+		v.warn(fmt.Sprintf("The package license %s is not a known SPDX license identifier", id))
+	}
+	if str.IsEmpty(meta.RepositoryURL) {
+		v.warn("The package repository is not specified")
+	}
+	for _, name := range ins.SkippedFiles {
+		v.warn(fmt.Sprintf("%s exceeds maximum allowed size %d bytes, it is ignored", name, constants.ArchiveCapturedFileMaxSize))
+	}
+	if str.IsEmpty(ins.ReadmeFileName) {
+		v.warn(fmt.Sprintf("README not found in package contents, a default %s will be used", constants.DefaultReadmeFileName))
+	}
+	if str.IsEmpty(ins.LicenseFileName) {
+		v.warn("LICENSE file not found in package contents")
+	}
+
+	v.checkLicense()
+
+	v.checkGoMod()
+	v.checkImports()
+
+	if ins.SourcesTruncated {
+		v.warn(fmt.Sprintf("The Go sources exceed %d bytes, the API of the package is extracted partially", constants.ArchiveSourcesMaxSize))
+	}
+
+	return v, nil
+}
+
+// checkDependencies checks whether the GoPx dependencies exist in the registry and
+// have a published version which satisfies the constraint.
+func (v *Validation) checkDependencies() (err error) {
+	for _, name := range sortedStringKeys(v.Meta.Dependencies) {
+		if helper.DependencyKind(name) != constants.DependencyKindGoPx {
+			continue
+		}
+
+		if name == v.Meta.Name {
+			v.fail(http.StatusBadRequest, "Package must not depend on itself")
+			continue
+		}
+
+		pkgRows, err := Query("name = ?", "id ASC", "1", "", name)
+		if err != nil {
+			return err
+		}
+
+		if len(pkgRows) < 1 || pkgRows[0].Status == constants.PackageStatusDeleting {
+			v.fail(http.StatusBadRequest, fmt.Sprintf("Dependency %s does not exist in GoPx registry", name))
+			continue
+		}
+
+		constraint := v.Meta.Dependencies[name]
+		version, err := ResolveVersion(name, constraint)
+		if err != nil {
+			return err
+		}
+
+		if str.IsEmpty(version) {
+			v.fail(http.StatusBadRequest, fmt.Sprintf("No published version of dependency %s satisfies %s", name, constraint))
+		}
+	}
+
+	return nil
+}
+
+// checkPublisher checks whether the owner can publish the version, and finds the
+// existing package if the version is a new release.
+func (v *Validation) checkPublisher(ownerInfo *user.QueryRow) (err error) {
+	pkgRows, err := Query("name = ?", "id ASC", "1", "", v.Meta.Name)
+	if err != nil {
+		return
+	}
+
+	if len(pkgRows) < 1 {
+		return nil
+	}
+
+	pkgRow := pkgRows[0]
+
+	if pkgRow.OwnerUsername != ownerInfo.Username {
+		v.fail(http.StatusUnauthorized, "Bad credentials")
+		return nil
+	}
+	v.Package = pkgRow
+
+	if pkgRow.Status == constants.PackageStatusDeleting {
+		v.fail(http.StatusConflict, fmt.Sprintf("Package %s is being deleted", pkgRow.Name))
+	}
+
+	// The version is invalid, it is already reported.
+	if _, err := helper.SanitizePackageVersion(v.Meta.Version); err != nil {
+		return nil
+	}
+
+	ok, err := VersionExists(pkgRow.Name, v.Meta.Version)
+	if err != nil {
+		return
+	}
+
+	if ok {
+		v.fail(http.StatusBadRequest, fmt.Sprintf("Package version %s already exists", v.Meta.Version))
+	}
+
+	return nil
+}
@@ -0,0 +1,4 @@
+/*
+Package readme provides controllers to render the README files of the packages.
+*/
+package readme
@@ -0,0 +1,85 @@
+package readme
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// policy sanitizes the rendered HTML, the README files are written by the package
+// authors so those are not trusted.
+var policy = bluemonday.UGCPolicy()
+
+// RenderHTML renders a README file written in GitHub Flavored Markdown as sanitized HTML.
+// The relative links and images are rewritten to point to the files of the package version
+// under filesURL, the raw HTML blocks are kept but sanitized along with the rest.
+func RenderHTML(content []byte, filesURL string) (rendered []byte, err error) {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(util.Prioritized(&linkRewriter{filesURL: filesURL}, 100)),
+		),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	var buff bytes.Buffer
+	err = md.Convert(content, &buff)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to render the README file")
+		return
+	}
+
+	return policy.SanitizeBytes(buff.Bytes()), nil
+}
+
+// linkRewriter rewrites the destinations of the relative links and images of a README.
+type linkRewriter struct {
+	filesURL string
+}
+
+// Transform implements parser.ASTTransformer.
+func (lr *linkRewriter) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch v := n.(type) {
+		case *ast.Link:
+			v.Destination = lr.rewrite(v.Destination)
+		case *ast.Image:
+			v.Destination = lr.rewrite(v.Destination)
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// rewrite resolves a relative link against the root of the package, the absolute
+// links, fragments and the links escaping the package are left as they are.
+func (lr *linkRewriter) rewrite(dest []byte) []byte {
+	u, err := url.Parse(string(dest))
+	if err != nil || u.IsAbs() || u.Host != "" || u.Path == "" {
+		return dest
+	}
+
+	p := path.Clean("/" + u.Path)
+	if strings.HasPrefix(u.Path, "../") || u.Path == ".." || p == "/" {
+		return dest
+	}
+
+	u.Path = strings.TrimSuffix(lr.filesURL, "/") + p
+
+	return []byte(u.String())
+}
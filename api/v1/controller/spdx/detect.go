@@ -0,0 +1,97 @@
+package spdx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fingerprint identifies a license by the phrases of its text, all the phrases must be
+// present and none of the excluded ones. The phrases are normalized by normalizeText.
+type fingerprint struct {
+	id       string
+	phrases  []string
+	excluded []string
+}
+
+const (
+	bsdRedistribution = "redistribution and use in source and binary forms with or without modification are permitted provided that the following conditions are met"
+	bsdEndorsement    = "neither the name of"
+	bsdAdvertising    = "all advertising materials mentioning features or use of this software"
+	mitPermission     = "permission is hereby granted free of charge to any person obtaining a copy of this software and associated documentation files"
+	mitNotice         = "the above copyright notice and this permission notice shall be included in all copies or substantial portions of the software"
+	iscPermission     = "permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted"
+	iscNotice         = "provided that the above copyright notice and this permission notice appear in all copies"
+)
+
+// corpus holds the fingerprints of the common licenses, ordered so that the licenses
+// whose texts include the phrases of another come first e.g. LGPL before GPL.
+var corpus = []*fingerprint{
+	{id: "AGPL-3.0-only", phrases: []string{"gnu affero general public license version 3 19 november 2007"}},
+	{id: "LGPL-3.0-only", phrases: []string{"gnu lesser general public license version 3 29 june 2007"}},
+	{id: "LGPL-2.1-only", phrases: []string{"gnu lesser general public license version 2 1 february 1999"}},
+	{id: "LGPL-2.0-only", phrases: []string{"gnu library general public license version 2 june 1991"}},
+	{id: "GPL-3.0-only", phrases: []string{"gnu general public license version 3 29 june 2007"}},
+	{id: "GPL-2.0-only", phrases: []string{"gnu general public license version 2 june 1991"}},
+	{id: "Apache-2.0", phrases: []string{"apache license version 2 0 january 2004"}},
+	{id: "Apache-2.0", phrases: []string{"licensed under the apache license version 2 0"}},
+	{id: "MPL-2.0", phrases: []string{"mozilla public license version 2 0"}},
+	{id: "MPL-1.1", phrases: []string{"mozilla public license version 1 1"}},
+	{id: "EPL-2.0", phrases: []string{"eclipse public license v 2 0"}},
+	{id: "EPL-1.0", phrases: []string{"eclipse public license v 1 0"}},
+	{id: "EUPL-1.2", phrases: []string{"european union public licence v 1 2"}},
+	{id: "EUPL-1.1", phrases: []string{"european union public licence v 1 1"}},
+	{id: "BSL-1.0", phrases: []string{"boost software license version 1 0"}},
+	{id: "Artistic-2.0", phrases: []string{"the artistic license 2 0"}},
+	{id: "CC0-1.0", phrases: []string{"cc0 1 0 universal"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+	{id: "WTFPL", phrases: []string{"do what the fuck you want to public license"}},
+	{id: "Zlib", phrases: []string{"this software is provided as is without any express or implied warranty", "the origin of this software must not be misrepresented"}},
+	{id: "BSD-4-Clause", phrases: []string{bsdRedistribution, bsdAdvertising}},
+	{id: "BSD-3-Clause", phrases: []string{bsdRedistribution, bsdEndorsement}},
+	{id: "BSD-2-Clause", phrases: []string{bsdRedistribution}, excluded: []string{bsdEndorsement, bsdAdvertising}},
+	{id: "X11", phrases: []string{mitPermission, "except as contained in this notice the name of the x consortium"}},
+	{id: "MIT", phrases: []string{mitPermission, mitNotice}},
+	{id: "MIT-0", phrases: []string{mitPermission}, excluded: []string{mitNotice}},
+	{id: "ISC", phrases: []string{iscPermission, iscNotice}},
+	{id: "0BSD", phrases: []string{iscPermission}, excluded: []string{iscNotice}},
+}
+
+var textRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeText lowercases a license text and replaces everything other than the
+// letters and digits with single spaces, so that the formatting does not matter.
+func normalizeText(text string) string {
+	return " " + strings.TrimSpace(textRe.ReplaceAllString(strings.ToLower(text), " ")) + " "
+}
+
+// Detect detects the license of a license file by the bundled fingerprints, it returns
+// the SPDX identifier or an empty string if the license is not recognized. The "only"
+// variants are returned for the licenses having "or later" variants, since the texts
+// of those do not differ.
+func Detect(content []byte) string {
+	text := normalizeText(string(content))
+
+	for _, fp := range corpus {
+		if fp.matches(text) {
+			return fp.id
+		}
+	}
+
+	return ""
+}
+
+func (fp *fingerprint) matches(text string) bool {
+	for _, p := range fp.phrases {
+		if !strings.Contains(text, " "+p+" ") {
+			return false
+		}
+	}
+
+	for _, p := range fp.excluded {
+		if strings.Contains(text, " "+p+" ") {
+			return false
+		}
+	}
+
+	return true
+}
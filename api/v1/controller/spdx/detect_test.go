@@ -0,0 +1,83 @@
+package spdx
+
+import "testing"
+
+const (
+	mitText = `MIT License
+
+Copyright (c) 2019 GoPx
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND.
+`
+
+	mit0Text = `MIT No Attribution
+
+Copyright 2019 GoPx
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this
+software and associated documentation files (the "Software"), to deal in the Software
+without restriction, including without limitation the rights to use, copy, modify,
+merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND.
+`
+
+	bsd2Text = `Copyright (c) 2019, GoPx
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS".
+`
+
+	bsd3Text = bsd2Text + `
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.
+`
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "MIT", content: mitText, want: "MIT"},
+		{name: "MIT-0", content: mit0Text, want: "MIT-0"},
+		{name: "BSD-2-Clause", content: bsd2Text, want: "BSD-2-Clause"},
+		{name: "BSD-3-Clause", content: bsd3Text, want: "BSD-3-Clause"},
+		{name: "Apache-2.0 header", content: "Licensed under the Apache License, Version 2.0 (the \"License\");", want: "Apache-2.0"},
+		{name: "GPL-3.0", content: "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", want: "GPL-3.0-only"},
+		{name: "LGPL-3.0", content: "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", want: "LGPL-3.0-only"},
+		{name: "unknown", content: "All rights reserved.", want: ""},
+		{name: "empty", content: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.content)); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package spdx provides controllers to normalize the SPDX license expressions and to detect
+the licenses from the contents of the license files.
+*/
+package spdx
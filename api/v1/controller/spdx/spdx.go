@@ -0,0 +1,474 @@
+package spdx
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// licenses holds the SPDX identifiers of the common licenses along with their full names.
+var licenses = map[string]string{
+	"0BSD":                          "BSD Zero Clause License",
+	"AFL-3.0":                       "Academic Free License v3.0",
+	"AGPL-3.0-only":                 "GNU Affero General Public License v3.0 only",
+	"AGPL-3.0-or-later":             "GNU Affero General Public License v3.0 or later",
+	"Apache-1.0":                    "Apache License 1.0",
+	"Apache-1.1":                    "Apache License 1.1",
+	"Apache-2.0":                    "Apache License 2.0",
+	"Artistic-2.0":                  "Artistic License 2.0",
+	"BSD-1-Clause":                  "BSD 1-Clause License",
+	"BSD-2-Clause":                  "BSD 2-Clause \"Simplified\" License",
+	"BSD-2-Clause-Patent":           "BSD-2-Clause Plus Patent License",
+	"BSD-3-Clause":                  "BSD 3-Clause \"New\" or \"Revised\" License",
+	"BSD-3-Clause-Attribution":      "BSD with attribution",
+	"BSD-3-Clause-Clear":            "BSD 3-Clause Clear License",
+	"BSD-4-Clause":                  "BSD 4-Clause \"Original\" or \"Old\" License",
+	"BSL-1.0":                       "Boost Software License 1.0",
+	"CC-BY-3.0":                     "Creative Commons Attribution 3.0 Unported",
+	"CC-BY-4.0":                     "Creative Commons Attribution 4.0 International",
+	"CC-BY-SA-3.0":                  "Creative Commons Attribution Share Alike 3.0 Unported",
+	"CC-BY-SA-4.0":                  "Creative Commons Attribution Share Alike 4.0 International",
+	"CC0-1.0":                       "Creative Commons Zero v1.0 Universal",
+	"CDDL-1.0":                      "Common Development and Distribution License 1.0",
+	"CDDL-1.1":                      "Common Development and Distribution License 1.1",
+	"ECL-2.0":                       "Educational Community License v2.0",
+	"EPL-1.0":                       "Eclipse Public License 1.0",
+	"EPL-2.0":                       "Eclipse Public License 2.0",
+	"EUPL-1.1":                      "European Union Public License 1.1",
+	"EUPL-1.2":                      "European Union Public License 1.2",
+	"GPL-1.0-only":                  "GNU General Public License v1.0 only",
+	"GPL-1.0-or-later":              "GNU General Public License v1.0 or later",
+	"GPL-2.0-only":                  "GNU General Public License v2.0 only",
+	"GPL-2.0-or-later":              "GNU General Public License v2.0 or later",
+	"GPL-3.0-only":                  "GNU General Public License v3.0 only",
+	"GPL-3.0-or-later":              "GNU General Public License v3.0 or later",
+	"ISC":                           "ISC License",
+	"LGPL-2.0-only":                 "GNU Library General Public License v2 only",
+	"LGPL-2.0-or-later":             "GNU Library General Public License v2 or later",
+	"LGPL-2.1-only":                 "GNU Lesser General Public License v2.1 only",
+	"LGPL-2.1-or-later":             "GNU Lesser General Public License v2.1 or later",
+	"LGPL-3.0-only":                 "GNU Lesser General Public License v3.0 only",
+	"LGPL-3.0-or-later":             "GNU Lesser General Public License v3.0 or later",
+	"MIT":                           "MIT License",
+	"MIT-0":                         "MIT No Attribution",
+	"MPL-1.1":                       "Mozilla Public License 1.1",
+	"MPL-2.0":                       "Mozilla Public License 2.0",
+	"MPL-2.0-no-copyleft-exception": "Mozilla Public License 2.0 (no copyleft exception)",
+	"MS-PL":                         "Microsoft Public License",
+	"MS-RL":                         "Microsoft Reciprocal License",
+	"NCSA":                          "University of Illinois/NCSA Open Source License",
+	"OFL-1.1":                       "SIL Open Font License 1.1",
+	"OpenSSL":                       "OpenSSL License",
+	"OSL-3.0":                       "Open Software License 3.0",
+	"PostgreSQL":                    "PostgreSQL License",
+	"Python-2.0":                    "Python License 2.0",
+	"Unlicense":                     "The Unlicense",
+	"UPL-1.0":                       "Universal Permissive License v1.0",
+	"WTFPL":                         "Do What The F*ck You Want To Public License",
+	"X11":                           "X11 License",
+	"Zlib":                          "zlib License",
+	"ZPL-2.1":                       "Zope Public License 2.1",
+}
+
+// deprecated maps the deprecated SPDX identifiers to their replacements.
+var deprecated = map[string]string{
+	"AGPL-3.0":  "AGPL-3.0-only",
+	"AGPL-3.0+": "AGPL-3.0-or-later",
+	"GPL-1.0":   "GPL-1.0-only",
+	"GPL-1.0+":  "GPL-1.0-or-later",
+	"GPL-2.0":   "GPL-2.0-only",
+	"GPL-2.0+":  "GPL-2.0-or-later",
+	"GPL-3.0":   "GPL-3.0-only",
+	"GPL-3.0+":  "GPL-3.0-or-later",
+	"LGPL-2.0":  "LGPL-2.0-only",
+	"LGPL-2.0+": "LGPL-2.0-or-later",
+	"LGPL-2.1":  "LGPL-2.1-only",
+	"LGPL-2.1+": "LGPL-2.1-or-later",
+	"LGPL-3.0":  "LGPL-3.0-only",
+	"LGPL-3.0+": "LGPL-3.0-or-later",
+}
+
+// exceptions holds the SPDX identifiers of the common license exceptions used with WITH.
+var exceptions = []string{
+	"Autoconf-exception-3.0",
+	"Bison-exception-2.2",
+	"Classpath-exception-2.0",
+	"Font-exception-2.0",
+	"GCC-exception-3.1",
+	"Linux-syscall-note",
+	"LLVM-exception",
+	"OpenJDK-assembly-exception-1.0",
+	"Qt-LGPL-exception-1.1",
+	"Universal-FOSS-exception-1.0",
+}
+
+// aliases maps the common informal license names to the SPDX identifiers, the keys
+// are made by aliasKey e.g. "The MIT License" and "mit licence" are both "mit".
+var aliases = map[string]string{
+	"mit":                   "MIT",
+	"expat":                 "MIT",
+	"apache":                "Apache-2.0",
+	"apache2":               "Apache-2.0",
+	"apache20":              "Apache-2.0",
+	"apachev2":              "Apache-2.0",
+	"apachev20":             "Apache-2.0",
+	"asl2":                  "Apache-2.0",
+	"asl20":                 "Apache-2.0",
+	"gpl2":                  "GPL-2.0-only",
+	"gplv2":                 "GPL-2.0-only",
+	"gpl3":                  "GPL-3.0-only",
+	"gplv3":                 "GPL-3.0-only",
+	"lgpl21":                "LGPL-2.1-only",
+	"lgplv21":               "LGPL-2.1-only",
+	"lgpl3":                 "LGPL-3.0-only",
+	"lgplv3":                "LGPL-3.0-only",
+	"agpl3":                 "AGPL-3.0-only",
+	"agplv3":                "AGPL-3.0-only",
+	"bsd2":                  "BSD-2-Clause",
+	"bsd2clause":            "BSD-2-Clause",
+	"simplifiedbsd":         "BSD-2-Clause",
+	"freebsd":               "BSD-2-Clause",
+	"bsd3":                  "BSD-3-Clause",
+	"bsd3clause":            "BSD-3-Clause",
+	"newbsd":                "BSD-3-Clause",
+	"modifiedbsd":           "BSD-3-Clause",
+	"revisedbsd":            "BSD-3-Clause",
+	"mpl2":                  "MPL-2.0",
+	"mpl20":                 "MPL-2.0",
+	"mozillapublic20":       "MPL-2.0",
+	"isc":                   "ISC",
+	"unlicense":             "Unlicense",
+	"cc0":                   "CC0-1.0",
+	"cc010":                 "CC0-1.0",
+	"boost":                 "BSL-1.0",
+	"boostsoftware10":       "BSL-1.0",
+	"bsl":                   "BSL-1.0",
+	"zlib":                  "Zlib",
+	"wtfpl":                 "WTFPL",
+	"epl2":                  "EPL-2.0",
+	"eclipsepublic20":       "EPL-2.0",
+	"postgresql":            "PostgreSQL",
+	"artistic2":             "Artistic-2.0",
+	"artistic20":            "Artistic-2.0",
+	"universalpermissive10": "UPL-1.0",
+}
+
+var (
+	lowerIDs        = map[string]string{}
+	lowerExceptions = map[string]string{}
+	families        = map[string][]string{}
+	idRe            = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+	aliasWordsRe    = regexp.MustCompile(`\b(the|license|licence|version)\b`)
+	nonAlnumRe      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func init() {
+	for id := range licenses {
+		lowerIDs[strings.ToLower(id)] = id
+		families[family(id)] = append(families[family(id)], id)
+	}
+	for _, ids := range families {
+		sort.Strings(ids)
+	}
+	for id, repl := range deprecated {
+		lowerIDs[strings.ToLower(id)] = repl
+	}
+	for _, id := range exceptions {
+		lowerExceptions[strings.ToLower(id)] = id
+	}
+}
+
+// Name returns the full name of a license by its SPDX identifier, or an empty string
+// if the identifier is not known.
+func Name(id string) string {
+	return licenses[id]
+}
+
+// Normalize validates a license expression and returns it in the canonical SPDX form,
+// e.g. "mit OR apache-2.0" becomes "MIT OR Apache-2.0" and "GPL-3.0" becomes "GPL-3.0-only".
+// The common informal names of a single license are accepted too, e.g. "MIT License"
+// and "Apache2". The custom licenses are referred as LicenseRef-<name>. Only the common
+// licenses are bundled, so the well-formed identifiers which are not known are kept
+// verbatim, those are reported by Unknown.
+func Normalize(expr string) (normalized string, err error) {
+	expr = strings.TrimSpace(expr)
+
+	normalized, ok := parseExpression(expr, false)
+	if ok {
+		return normalized, nil
+	}
+
+	if isName(expr) {
+		if id, ok := aliases[aliasKey(expr)]; ok {
+			return id, nil
+		}
+	}
+
+	normalized, ok = parseExpression(expr, true)
+	if ok {
+		return normalized, nil
+	}
+
+	return "", errors.Errorf("Package license %q is not a valid SPDX license expression e.g. MIT or Apache-2.0", expr)
+}
+
+// aliasKey reduces an informal license name to the lowercase letters and digits,
+// without the filler words.
+func aliasKey(name string) string {
+	key := aliasWordsRe.ReplaceAllString(strings.ToLower(name), "")
+	return nonAlnumRe.ReplaceAllString(key, "")
+}
+
+// isName checks whether a license expression may be an informal name of a single license,
+// i.e. it has no parentheses and no operators.
+func isName(expr string) bool {
+	for _, t := range tokenize(expr) {
+		if t == "(" || t == ")" || isOperator(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Unknown returns the license and exception identifiers of a normalized license expression
+// which are not known, the license references are not included.
+func Unknown(expr string) (ids []string) {
+	tokens := tokenize(expr)
+	for i, t := range tokens {
+		if t == "(" || t == ")" || isOperator(t) || isRef(t) {
+			continue
+		}
+
+		lower := strings.ToLower(t)
+		if i > 0 && strings.ToUpper(tokens[i-1]) == "WITH" {
+			if _, ok := lowerExceptions[lower]; !ok {
+				ids = append(ids, t)
+			}
+			continue
+		}
+
+		if _, ok := lowerIDs[strings.TrimSuffix(lower, "+")]; !ok {
+			ids = append(ids, t)
+		}
+	}
+
+	return ids
+}
+
+// IDs returns the license identifiers used in a normalized license expression, the
+// exceptions are not included.
+func IDs(expr string) (ids []string) {
+	tokens := tokenize(expr)
+	for i, t := range tokens {
+		if t == "(" || t == ")" || isOperator(t) {
+			continue
+		}
+		if i > 0 && strings.ToUpper(tokens[i-1]) == "WITH" {
+			continue
+		}
+		ids = append(ids, t)
+	}
+
+	return ids
+}
+
+// Covers checks whether a license expression includes a license, the "only" and
+// "or later" variants of a license are considered as the same since their license
+// texts do not differ.
+func Covers(expr, id string) bool {
+	for _, v := range IDs(expr) {
+		if family(v) == family(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Variants returns the canonical identifiers of the licenses which Covers considers as
+// the same as a license identifier, e.g. "GPL-3.0-only" and "GPL-3.0-or-later" for
+// "GPL-3.0", or "MPL-2.0" and "MPL-2.0+" for "MPL-2.0". The unknown identifiers have
+// only their "+" variant along with themselves.
+func Variants(id string) (ids []string) {
+	f := family(id)
+	if known, ok := families[f]; ok {
+		ids = append(ids, known...)
+		if _, ok := licenses[f]; ok {
+			ids = append(ids, f+"+")
+		}
+		return ids
+	}
+
+	return []string{f, f + "+"}
+}
+
+// family strips the "only" and "or later" suffixes of a license identifier.
+func family(id string) string {
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-only")
+	return strings.TrimSuffix(id, "-or-later")
+}
+
+func isOperator(t string) bool {
+	switch strings.ToUpper(t) {
+	case "AND", "OR", "WITH":
+		return true
+	}
+	return false
+}
+
+// tokenize splits a license expression into the identifiers, the operators and the parentheses.
+func tokenize(expr string) (tokens []string) {
+	expr = strings.Replace(strings.Replace(expr, "(", " ( ", -1), ")", " ) ", -1)
+	return strings.Fields(expr)
+}
+
+// expressionParser parses the license expressions by the grammar:
+//
+//	expression = and-expression { "OR" and-expression }
+//	and-expression = with-expression { "AND" with-expression }
+//	with-expression = simple-expression [ "WITH" exception ]
+//	simple-expression = license-id [ "+" ] | license-ref | "(" expression ")"
+//
+// The unknown identifiers are accepted verbatim if the parser is lenient.
+type expressionParser struct {
+	tokens  []string
+	pos     int
+	out     []string
+	lenient bool
+}
+
+// parseExpression parses a license expression and returns it with the canonical
+// identifiers and the uppercase operators.
+func parseExpression(expr string, lenient bool) (normalized string, ok bool) {
+	p := &expressionParser{tokens: tokenize(expr), lenient: lenient}
+	if len(p.tokens) == 0 || !p.expression() || p.pos != len(p.tokens) {
+		return "", false
+	}
+
+	normalized = strings.Join(p.out, " ")
+	normalized = strings.Replace(strings.Replace(normalized, "( ", "(", -1), " )", ")", -1)
+
+	return normalized, true
+}
+
+func (p *expressionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *expressionParser) operator(op string) bool {
+	if strings.ToUpper(p.peek()) != op {
+		return false
+	}
+	p.out = append(p.out, op)
+	p.pos++
+	return true
+}
+
+func (p *expressionParser) expression() bool {
+	if !p.andExpression() {
+		return false
+	}
+	for p.operator("OR") {
+		if !p.andExpression() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *expressionParser) andExpression() bool {
+	if !p.withExpression() {
+		return false
+	}
+	for p.operator("AND") {
+		if !p.withExpression() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *expressionParser) withExpression() bool {
+	if !p.simpleExpression() {
+		return false
+	}
+	if !p.operator("WITH") {
+		return true
+	}
+
+	exception, ok := lowerExceptions[strings.ToLower(p.peek())]
+	if !ok {
+		if !p.lenient || !idRe.MatchString(p.peek()) {
+			return false
+		}
+		exception = p.peek()
+	}
+	p.out = append(p.out, exception)
+	p.pos++
+	return true
+}
+
+func (p *expressionParser) simpleExpression() bool {
+	t := p.peek()
+	switch {
+	case t == "":
+		return false
+	case t == "(":
+		p.out = append(p.out, "(")
+		p.pos++
+		if !p.expression() || p.peek() != ")" {
+			return false
+		}
+		p.out = append(p.out, ")")
+		p.pos++
+		return true
+	case t == ")" || isOperator(t):
+		return false
+	}
+
+	id, ok := licenseID(t, p.lenient)
+	if !ok {
+		return false
+	}
+	p.out = append(p.out, id)
+	p.pos++
+	return true
+}
+
+// licenseID returns the canonical form of a license identifier or a license reference,
+// the unknown well-formed identifiers are returned verbatim if lenient is set.
+func licenseID(t string, lenient bool) (id string, ok bool) {
+	lower := strings.ToLower(t)
+	if isRef(t) {
+		return t, len(t) > len("LicenseRef-")
+	}
+
+	if id, ok := lowerIDs[lower]; ok {
+		return id, true
+	}
+
+	// The "or later" versions of the licenses without an "or-later" identifier.
+	if strings.HasSuffix(lower, "+") {
+		if id, ok := lowerIDs[strings.TrimSuffix(lower, "+")]; ok {
+			return id + "+", true
+		}
+	}
+
+	if lenient && idRe.MatchString(strings.TrimSuffix(t, "+")) {
+		return t, true
+	}
+
+	return "", false
+}
+
+// isRef checks whether a token is a license reference.
+func isRef(t string) bool {
+	lower := strings.ToLower(t)
+	return strings.HasPrefix(lower, "licenseref-") || strings.HasPrefix(lower, "documentref-")
+}
@@ -0,0 +1,130 @@
+package spdx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{expr: "MIT", want: "MIT"},
+		{expr: " mit ", want: "MIT"},
+		{expr: "mit license", want: "MIT"},
+		{expr: "The MIT Licence", want: "MIT"},
+		{expr: "Apache2", want: "Apache-2.0"},
+		{expr: "Apache License, Version 2.0", want: "Apache-2.0"},
+		{expr: "apache-2.0", want: "Apache-2.0"},
+		{expr: "GPL-3.0", want: "GPL-3.0-only"},
+		{expr: "GPL-3.0+", want: "GPL-3.0-or-later"},
+		{expr: "gplv3", want: "GPL-3.0-only"},
+		{expr: "MPL-2.0+", want: "MPL-2.0+"},
+		{expr: "mit or apache-2.0", want: "MIT OR Apache-2.0"},
+		{expr: "(MIT OR Apache-2.0) and bsd-3-clause", want: "(MIT OR Apache-2.0) AND BSD-3-Clause"},
+		{expr: "GPL-2.0-or-later with classpath-exception-2.0", want: "GPL-2.0-or-later WITH Classpath-exception-2.0"},
+		{expr: "LicenseRef-Proprietary", want: "LicenseRef-Proprietary"},
+		{expr: "MIT OR LicenseRef-Commercial", want: "MIT OR LicenseRef-Commercial"},
+		{expr: "Foo-1.0", want: "Foo-1.0"},
+		{expr: "MIT WITH Foo-exception", want: "MIT WITH Foo-exception"},
+		{expr: "", wantErr: true},
+		{expr: "MIT OR", wantErr: true},
+		{expr: "(MIT", wantErr: true},
+		{expr: "MIT)", wantErr: true},
+		{expr: "MIT AND AND Apache-2.0", wantErr: true},
+		{expr: "WITH MIT", wantErr: true},
+		{expr: "LicenseRef-", wantErr: true},
+		{expr: "my license!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Normalize(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknown(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "MIT OR Apache-2.0", want: nil},
+		{expr: "MPL-2.0+", want: nil},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", want: nil},
+		{expr: "LicenseRef-Proprietary AND DocumentRef-spdx:LicenseRef-X", want: nil},
+		{expr: "Foo-1.0 OR MIT", want: []string{"Foo-1.0"}},
+		{expr: "(Foo-1.0+ AND Bar)", want: []string{"Foo-1.0+", "Bar"}},
+		{expr: "MIT WITH Foo-exception", want: []string{"Foo-exception"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := Unknown(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unknown(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		expr string
+		id   string
+		want bool
+	}{
+		{expr: "MIT", id: "MIT", want: true},
+		{expr: "MIT OR Apache-2.0", id: "Apache-2.0", want: true},
+		{expr: "(MIT AND BSD-3-Clause)", id: "BSD-3-Clause", want: true},
+		{expr: "GPL-3.0-or-later", id: "GPL-3.0-only", want: true},
+		{expr: "GPL-2.0+", id: "GPL-2.0-only", want: true},
+		{expr: "MPL-2.0+", id: "MPL-2.0", want: true},
+		{expr: "MIT", id: "MIT-0", want: false},
+		{expr: "BSD-2-Clause", id: "BSD-3-Clause", want: false},
+		{expr: "LGPL-3.0-only", id: "GPL-3.0-only", want: false},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", id: "Classpath-exception-2.0", want: false},
+		{expr: "LicenseRef-MIT", id: "MIT", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr+"/"+tt.id, func(t *testing.T) {
+			if got := Covers(tt.expr, tt.id); got != tt.want {
+				t.Errorf("Covers(%q, %q) = %v, want %v", tt.expr, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{id: "GPL-3.0-only", want: []string{"GPL-3.0-only", "GPL-3.0-or-later"}},
+		{id: "GPL-3.0-or-later", want: []string{"GPL-3.0-only", "GPL-3.0-or-later"}},
+		{id: "MIT", want: []string{"MIT", "MIT+"}},
+		{id: "MPL-2.0+", want: []string{"MPL-2.0", "MPL-2.0+"}},
+		{id: "Foo-1.0", want: []string{"Foo-1.0", "Foo-1.0+"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := Variants(tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variants(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+			for _, v := range tt.want {
+				if !Covers(v, tt.id) {
+					t.Errorf("Covers(%q, %q) = false for a variant", v, tt.id)
+				}
+			}
+		})
+	}
+}
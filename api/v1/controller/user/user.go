@@ -404,3 +404,137 @@ func UpdateInfo(userID uint64, data *MutationData) (user *QueryRow, err error) {
 
 	return
 }
+
+// ChangeUsername changes the username of an user and keeps a record of the old
+// username so that requests to it can be redirected to the new one.
+func ChangeUsername(userID uint64, newUsername string) (user *QueryRow, err error) {
+	dbConn := database.Conn()
+	tx, err := dbConn.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to begin a transaction")
+		return
+	}
+
+	sqlSt := `
+	SELECT username
+	FROM users
+	WHERE id = ?
+	FOR UPDATE
+	`
+	var oldUsername string
+	err = tx.QueryRow(sqlSt, userID).Scan(&oldUsername)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to query current username")
+		return
+	}
+
+	sqlSt = `
+	SELECT changed_at
+	FROM user_username_changes
+	WHERE user_id = ?
+	ORDER BY changed_at DESC
+	LIMIT 1
+	`
+	var lastChangedAt time.Time
+	err = tx.QueryRow(sqlSt, userID).Scan(&lastChangedAt)
+	switch {
+	case err == sql.ErrNoRows:
+		err = nil
+	case err != nil:
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to query last username change")
+		return
+	case time.Since(lastChangedAt) < constants.UsernameChangeCooldown:
+		tx.Rollback()
+		err = constants.ErrUsernameChangeCooldown
+		return
+	}
+
+	sqlSt = `
+	SELECT COUNT(*)
+	FROM users
+	WHERE username = ? and id <> ?
+	`
+	var count uint64
+	err = tx.QueryRow(sqlSt, newUsername, userID).Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to check username availability")
+		return
+	}
+
+	if count > 0 {
+		tx.Rollback()
+		err = constants.ErrUsernameTaken
+		return
+	}
+
+	st := `
+	UPDATE users
+	SET username = ?
+	WHERE id = ?
+	`
+	_, err = tx.Exec(st, newUsername, userID)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to update username to users table")
+		return
+	}
+
+	st = `
+	INSERT INTO user_username_changes
+	(user_id, old_username, new_username)
+	VALUES
+	(?, ?, ?)
+	`
+	_, err = tx.Exec(st, userID, oldUsername, newUsername)
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to insert username change to user_username_changes table")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		err = errors.Wrap(err, "Failed to commit changes of username")
+		return
+	}
+
+	userRows, err := Query("id = ?", "id ASC", "1", "", userID)
+	if err != nil || len(userRows) < 1 {
+		err = errors.Wrap(err, "Failed to read updated user data")
+		return
+	}
+
+	user = userRows[0]
+
+	return
+}
+
+// RenamedTo returns the current username of the user who previously owned
+// the input username, or an empty string if the username was never changed.
+func RenamedTo(oldUsername string) (username string, err error) {
+	sqlSt := `
+	SELECT users.username
+	FROM user_username_changes
+	INNER JOIN users
+	ON users.id = user_username_changes.user_id
+	WHERE user_username_changes.old_username = ?
+	ORDER BY user_username_changes.changed_at DESC
+	LIMIT 1
+	`
+
+	dbConn := database.Conn()
+	err = dbConn.QueryRow(sqlSt, oldUsername).Scan(&username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		err = errors.Wrap(err, "Failed to query username changes")
+		return
+	}
+
+	return
+}
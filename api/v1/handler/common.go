@@ -1,12 +1,24 @@
 package handler
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"gopx.io/gopx-api/api/v1/auth"
 	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/archive"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/pkg"
 	"gopx.io/gopx-api/api/v1/controller/user"
+	"gopx.io/gopx-api/api/v1/types"
+	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
+	"gopx.io/gopx-common/log"
 	"gopx.io/gopx-common/str"
 )
 
@@ -73,3 +85,249 @@ func listOsNames(os string) []string {
 	}
 	return osList
 }
+
+// renamedUserURL rewrites the request URL of an '/users/:username' route
+// to point to the new username.
+func renamedUserURL(r *http.Request, oldUsername, newUsername string) string {
+	oURL := url.URL{
+		Path:     strings.Replace(r.URL.Path, "/users/"+oldUsername, "/users/"+newUsername, 1),
+		RawQuery: r.URL.RawQuery,
+	}
+
+	return oURL.String()
+}
+
+func publishedPackage(pr *pkg.QueryRow, version, status string, warnings []string) *types.PublishedPackage {
+	return &types.PublishedPackage{
+		Package: &types.Package{
+			Name:             pr.Name,
+			ID:               pr.ID,
+			Desc:             pr.Description,
+			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
+			Version:          pr.LatestVersion,
+			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
+			PublishedAt:      pr.PublishedAt,
+			UpdatedAt:        pr.LastReleasedAt,
+			License:          pr.License,
+			Homepage:         pr.HomepageURL,
+			RepositoryURL:    pr.RepositoryURL,
+			DocumentationURL: pr.DocumentationURL,
+			BugsURL:          pr.BugsURL,
+			Engines: types.Engines{
+				Go: pr.EnginesGO,
+			},
+			Os: listOsNames(pr.OS),
+		},
+		PublishedVersion: version,
+		PublishStatus:    status,
+		Warnings:         warnings,
+	}
+}
+
+// isAsyncRequest checks whether the client asked to process the request asynchronously,
+// either by the 'async' query param or by the 'Prefer: respond-async' header.
+func isAsyncRequest(r *http.Request) bool {
+	async := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("async")))
+	if async == "1" || async == "true" {
+		return true
+	}
+
+	for _, v := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.ToLower(strings.TrimSpace(v)) == "respond-async" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDryRunRequest checks whether the client asked to only validate the request by the 'dry_run' query param.
+func isDryRunRequest(r *http.Request) bool {
+	dryRun := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("dry_run")))
+	return dryRun == "1" || dryRun == "true"
+}
+
+// isStrictRequest checks whether the client asked for the strict validation by the 'strict' query param.
+func isStrictRequest(r *http.Request) bool {
+	strict := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("strict")))
+	return strict == "1" || strict == "true"
+}
+
+func packageVersion(sv *pkg.SingleVersion) types.PackageVersion {
+	pv := types.PackageVersion{
+		Version:      sv.Version,
+		Status:       sv.Status,
+		BuildStatus:  sv.BuildStatus,
+		NotesExcerpt: sv.NotesExcerpt,
+		ReleasedAt:   sv.ReleasedAT,
+	}
+
+	// The versions published before the digests were recorded have no integrity.
+	if !str.IsEmpty(sv.SHA256) {
+		pv.Integrity = &types.PackageIntegrity{
+			SHA256: sv.SHA256,
+			SHA512: sv.SHA512,
+		}
+	}
+
+	return pv
+}
+
+func packageDependencies(deps []*pkg.Dependency) []*types.PackageDependency {
+	pDeps := make([]*types.PackageDependency, len(deps))
+	for i, dep := range deps {
+		pDeps[i] = &types.PackageDependency{
+			Name:       dep.Name,
+			Constraint: dep.Constraint,
+			Kind:       dep.Kind,
+		}
+	}
+
+	return pDeps
+}
+
+func packageGoModule(gm *pkg.GoModule) *types.PackageGoModule {
+	pgm := &types.PackageGoModule{
+		Path:    gm.Path,
+		Go:      gm.GoVersion,
+		Require: make([]*types.PackageModuleRequire, len(gm.Requires)),
+		Replace: make([]*types.PackageModuleReplace, len(gm.Replaces)),
+	}
+
+	for i, r := range gm.Requires {
+		pgm.Require[i] = &types.PackageModuleRequire{
+			Path:     r.Path,
+			Version:  r.Version,
+			Indirect: r.Indirect,
+		}
+	}
+
+	for i, r := range gm.Replaces {
+		pgm.Replace[i] = &types.PackageModuleReplace{
+			OldPath:    r.OldPath,
+			OldVersion: r.OldVersion,
+			NewPath:    r.NewPath,
+			NewVersion: r.NewVersion,
+		}
+	}
+
+	return pgm
+}
+
+func packageSymbol(s *pkg.Symbol) *types.PackageSymbol {
+	return &types.PackageSymbol{
+		ImportPath: s.ImportPath,
+		Kind:       s.Kind,
+		Receiver:   s.Receiver,
+		Name:       s.Name,
+		Signature:  s.Signature,
+		Doc:        s.Doc,
+	}
+}
+
+// packageFileTree builds the file tree of a package version from its manifest, the
+// directories come before the files at every level.
+func packageFileTree(files []*archive.File) []*types.PackageFileNode {
+	root := &types.PackageFileNode{Type: constants.FileTypeDir, Children: []*types.PackageFileNode{}}
+	dirs := map[string]*types.PackageFileNode{".": root}
+
+	var dirOf func(dirPath string) *types.PackageFileNode
+	dirOf = func(dirPath string) *types.PackageFileNode {
+		if d, ok := dirs[dirPath]; ok {
+			return d
+		}
+
+		d := &types.PackageFileNode{
+			Name:     path.Base(dirPath),
+			Path:     dirPath,
+			Type:     constants.FileTypeDir,
+			Children: []*types.PackageFileNode{},
+		}
+		parent := dirOf(path.Dir(dirPath))
+		parent.Children = append(parent.Children, d)
+		dirs[dirPath] = d
+
+		return d
+	}
+
+	for _, f := range files {
+		d := dirOf(path.Dir(f.Path))
+		d.Children = append(d.Children, &types.PackageFileNode{
+			Name:   path.Base(f.Path),
+			Path:   f.Path,
+			Type:   constants.FileTypeFile,
+			Size:   f.Size,
+			Mode:   fmt.Sprintf("%04o", f.Mode),
+			SHA256: f.SHA256,
+		})
+
+		for p := path.Dir(f.Path); p != "."; p = path.Dir(p) {
+			dirs[p].Size += f.Size
+		}
+	}
+
+	for _, d := range dirs {
+		sort.SliceStable(d.Children, func(i, j int) bool {
+			a, b := d.Children[i], d.Children[j]
+			if a.Type != b.Type {
+				return a.Type == constants.FileTypeDir
+			}
+			return a.Name < b.Name
+		})
+	}
+
+	return root.Children
+}
+
+func packageImports(imports []*pkg.Import) *types.PackageImports {
+	pImports := &types.PackageImports{
+		Standard: []string{},
+		GoPx:     []string{},
+		Modules:  []string{},
+	}
+
+	for _, imp := range imports {
+		switch imp.Kind {
+		case constants.ImportKindStandard:
+			pImports.Standard = append(pImports.Standard, imp.Path)
+		case constants.ImportKindGoPx:
+			pImports.GoPx = append(pImports.GoPx, imp.Path)
+		default:
+			pImports.Modules = append(pImports.Modules, imp.Path)
+		}
+	}
+
+	return pImports
+}
+
+// requestedVersion finds the version of the '/packages/:packageName/versions/:version' route,
+// on failure it writes the error response and returns nil.
+func requestedVersion(w http.ResponseWriter, r *http.Request) *pkg.SingleVersion {
+	vars := mux.Vars(r)
+	return findVersion(w, r, vars["packageName"], vars["version"])
+}
+
+// findVersion finds a version of a package, on failure it writes the error response and returns nil.
+func findVersion(w http.ResponseWriter, r *http.Request, packageName, version string) *pkg.SingleVersion {
+	inputVersion, err := helper.SanitizePackageVersion(version)
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
+		return nil
+	}
+
+	sv, err := pkg.Version(packageName, inputVersion)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return nil
+	}
+
+	if sv == nil {
+		errorCtrl.Error404(w, r)
+		return nil
+	}
+
+	return sv
+}
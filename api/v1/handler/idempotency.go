@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/idempotency"
+	"gopx.io/gopx-api/api/v1/controller/pkg"
+	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-common/str"
+)
+
+// responseRecorder passes the response through to the client and keeps a
+// copy of it to be stored against the idempotency key.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	rr.statusCode = statusCode
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	if rr.statusCode == 0 {
+		rr.statusCode = http.StatusOK
+	}
+	rr.body.Write(data)
+	return rr.ResponseWriter.Write(data)
+}
+
+// Idempotent wraps a mutating handler to support the Idempotency-Key request header.
+// The first request with a key is processed normally and its response is stored
+// along with a fingerprint of the request. A retry with the same key and the same
+// request replays the stored response, and a reuse of the key for a different
+// request is rejected with "422 Unprocessable Entity".
+// Server errors are not stored, so the request can be retried with the same key.
+func Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(constants.IdempotencyKeyHeader))
+		if str.IsEmpty(key) {
+			next(w, r)
+			return
+		}
+
+		if utf8.RuneCountInString(key) > constants.IdempotencyKeyMaxLength {
+			errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be maximum %d characters long", constants.IdempotencyKeyHeader, constants.IdempotencyKeyMaxLength))
+			return
+		}
+
+		// Let the handler itself reject the unauthenticated requests.
+		ur, err := authUser(r.Header.Get("Authorization"))
+		if err != nil || ur == nil {
+			next(w, r)
+			return
+		}
+
+		// The body is spooled before the handler applies its own limits, so it is
+		// limited here to the largest body any wrapped handler accepts, the package data.
+		maxSize := pkg.MaxDataSize(ur)
+		maxBodySize := maxSize + constants.PackageUploadOverheadMaxSize
+		if r.ContentLength > maxBodySize {
+			writePackageDataTooLarge(w, r, maxSize)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		body, err := ioutil.TempFile("", constants.TempFileNamePrefixForIdempotentRequest)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+		defer os.RemoveAll(body.Name())
+		defer body.Close()
+
+		_, err = io.Copy(body, r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writePackageDataTooLarge(w, r, maxSize)
+				return
+			}
+
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Failed to read the request body")
+			return
+		}
+
+		fingerprint, err := fingerprintRequest(r, body)
+		if err != nil {
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Failed to read the request body")
+			return
+		}
+
+		_, err = body.Seek(0, 0)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+		r.Body = body
+
+		rec, created, err := idempotency.Begin(ur.ID, key, r.Method, r.URL.Path, fingerprint)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		if !created {
+			replayIdempotentResponse(w, r, rec, fingerprint)
+			return
+		}
+
+		rr := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				if err := idempotency.Abort(ur.ID, key); err != nil {
+					log.Error("Error %s", err)
+				}
+			}
+		}()
+
+		next(rr, r)
+
+		if rr.statusCode == 0 || rr.statusCode >= http.StatusInternalServerError {
+			return
+		}
+
+		err = idempotency.Complete(ur.ID, key, rr.statusCode, rr.Header(), rr.body.Bytes())
+		if err != nil {
+			log.Error("Error %s", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// fingerprintRequest creates a fingerprint of the request from its method, URL and
+// spooled body. The parts of a multipart body are hashed instead of the raw body, since
+// the clients usually generate a new multipart boundary for every retry.
+func fingerprintRequest(r *http.Request, body io.ReadSeeker) (fingerprint string, err error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+
+	_, err = body.Seek(0, 0)
+	if err != nil {
+		return
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") || str.IsEmpty(params["boundary"]) {
+		_, err = io.Copy(hash, body)
+		if err != nil {
+			return
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s\n%s\n", p.FormName(), p.FileName())
+		_, err = io.Copy(hash, p)
+		p.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, r *http.Request, rec *idempotency.QueryRow, fingerprint string) {
+	if rec.Fingerprint != fingerprint {
+		errorCtrl.Error(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("%s is already used for a different request", constants.IdempotencyKeyHeader))
+		return
+	}
+
+	if rec.Status == constants.IdempotencyKeyStatusProcessing {
+		errorCtrl.Error(w, r, http.StatusConflict, fmt.Sprintf("A request with the same %s is being processed", constants.IdempotencyKeyHeader))
+		return
+	}
+
+	headers := w.Header()
+	for name, values := range rec.Headers {
+		headers[name] = values
+	}
+	headers.Set("Idempotent-Replayed", "true")
+
+	helper.WriteResponse(w, r, rec.Body, rec.StatusCode)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/job"
+	"gopx.io/gopx-api/api/v1/controller/pkg"
+	"gopx.io/gopx-api/api/v1/types"
+	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
+	"gopx.io/gopx-common/log"
+)
+
+// PublishJobGET returns the progress of an asynchronous publish job of the authenticated user.
+// Request: GET /publish-jobs/:jobID
+func PublishJobGET(w http.ResponseWriter, r *http.Request) {
+	ur, err := authUser(r.Header.Get("Authorization"))
+
+	if err != nil {
+		switch err {
+		case constants.ErrInternalServer:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		default:
+			errorCtrl.Error(w, r, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+	}
+
+	if ur == nil {
+		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	pj, err := job.Query(mux.Vars(r)["jobID"])
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if pj == nil || pj.UserID != ur.ID {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	pjData := &types.PublishJob{
+		ID:        pj.ID,
+		Status:    pj.Status,
+		Stage:     pj.Stage,
+		ErrorCode: pj.ErrorCode,
+		CreatedAt: pj.CreatedAt,
+		UpdatedAt: pj.UpdatedAt,
+	}
+
+	if pj.Status == constants.PublishJobStatusFailed {
+		pjData.Errors = pj.Errors
+		if len(pjData.Errors) == 0 {
+			pjData.Errors = []string{pj.ErrorMessage}
+		}
+		pjData.Warnings = pj.Warnings
+		pjData.Entries = pj.ErrorEntries
+	}
+
+	if pj.Status == constants.PublishJobStatusSucceeded {
+		pkgRows, err := pkg.Query("name = ?", "id ASC", "1", "", pj.PackageName)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		if len(pkgRows) > 0 {
+			pjData.Package = publishedPackage(pkgRows[0], pj.Version, pj.PublishStatus, pj.Warnings)
+		}
+	}
+
+	helper.WriteResponseValueOK(w, r, pjData)
+}
@@ -1,21 +1,30 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"os"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopx.io/gopx-common/str"
 
 	"github.com/gorilla/mux"
 
 	"gopx.io/gopx-api/api/v1/constants"
+	"gopx.io/gopx-api/api/v1/controller/docs"
 	"gopx.io/gopx-api/api/v1/controller/helper"
 	"gopx.io/gopx-api/api/v1/controller/pkg"
+	"gopx.io/gopx-api/api/v1/controller/spdx"
 	"gopx.io/gopx-api/api/v1/types"
 	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
+	"gopx.io/gopx-api/pkg/controller/storage"
 	"gopx.io/gopx-common/log"
 )
 
@@ -27,6 +36,7 @@ import (
 // 3. updated
 // 4. name
 // 5. id
+// 6. dependents
 func PackagesGET(w http.ResponseWriter, r *http.Request) {
 	qParams := r.URL.Query()
 
@@ -72,8 +82,10 @@ func PackagesGET(w http.ResponseWriter, r *http.Request) {
 			ID:               pr.ID,
 			Desc:             pr.Description,
 			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
 			Version:          pr.LatestVersion,
 			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
 			PublishedAt:      pr.PublishedAt,
 			UpdatedAt:        pr.LastReleasedAt,
 			License:          pr.License,
@@ -112,13 +124,23 @@ func SinglePackageGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	pr := pkgRows[0]
+
+	commands, err := pkg.Commands(pr.ID, pr.LatestVersion)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
 	pkg := &types.Package{
 		Name:             pr.Name,
 		ID:               pr.ID,
 		Desc:             pr.Description,
 		Owner:            pr.OwnerUsername,
+		Status:           pr.Status,
 		Version:          pr.LatestVersion,
 		Downloads:        pr.Downloads,
+		DependentsCount:  pr.DependentsCount,
 		PublishedAt:      pr.PublishedAt,
 		UpdatedAt:        pr.LastReleasedAt,
 		License:          pr.License,
@@ -129,7 +151,8 @@ func SinglePackageGET(w http.ResponseWriter, r *http.Request) {
 		Engines: types.Engines{
 			Go: pr.EnginesGO,
 		},
-		Os: listOsNames(pr.OS),
+		Os:       listOsNames(pr.OS),
+		Commands: commands,
 	}
 
 	helper.WriteResponseValueOK(w, r, pkg)
@@ -137,12 +160,16 @@ func SinglePackageGET(w http.ResponseWriter, r *http.Request) {
 
 // SearchPackagesGET performs a search query among all public packages.
 // Request: GET /search/packages?q=websocket+in:name,desc+created:>2017-01-01&sort=downloads,id&order=desc&page=1&per_page=10
+// Packages having a command: GET /search/packages?q=websocket+has:command:test
+// Packages exporting a symbol: GET /search/packages?q=symbol:ParseRFC3339
+// Packages under a license: GET /search/packages?q=license:Apache-2.0
 // Sorting can be performed on:
 // 1. downloads
 // 2. created
 // 3. updated
 // 4. name
 // 5. id
+// 6. dependents
 func SearchPackagesGET(w http.ResponseWriter, r *http.Request) {
 	params := r.URL.Query()
 
@@ -157,7 +184,8 @@ func SearchPackagesGET(w http.ResponseWriter, r *http.Request) {
 	sq := pkg.SearchQuery{}
 	q = strings.TrimSpace(q)
 	parts := str.SplitSpace(q)
-	re, _ := regexp.Compile("^([^:]+)\\:([^:]+)$")
+	// Split on the first colon only, the values of some qualifiers contain colons e.g. has:command:test
+	re, _ := regexp.Compile("^([^:]+)\\:(.+)$")
 
 	for _, v := range parts {
 		match := re.FindStringSubmatch(v)
@@ -177,6 +205,12 @@ func SearchPackagesGET(w http.ResponseWriter, r *http.Request) {
 			sq.Downloads = qVal
 		case "owner":
 			sq.Owner = qVal
+		case "has":
+			sq.Has = qVal
+		case "symbol":
+			sq.Symbol = qVal
+		case "license":
+			sq.License = qVal
 		}
 	}
 
@@ -215,8 +249,10 @@ func SearchPackagesGET(w http.ResponseWriter, r *http.Request) {
 			ID:               pr.ID,
 			Desc:             pr.Description,
 			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
 			Version:          pr.LatestVersion,
 			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
 			PublishedAt:      pr.PublishedAt,
 			UpdatedAt:        pr.LastReleasedAt,
 			License:          pr.License,
@@ -243,6 +279,7 @@ func SearchPackagesGET(w http.ResponseWriter, r *http.Request) {
 // 3. updated
 // 4. name
 // 5. id
+// 6. dependents
 func DownloadsGET(w http.ResponseWriter, r *http.Request) {
 	qParams := r.URL.Query()
 
@@ -311,6 +348,29 @@ func DownloadsGET(w http.ResponseWriter, r *http.Request) {
 	helper.WriteResponseValueOK(w, r, pDownloads)
 }
 
+// LicensesGET returns the licenses of all public packages along with their package counts.
+// Request: GET /licenses
+func LicensesGET(w http.ResponseWriter, r *http.Request) {
+	licenses, err := pkg.Licenses()
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	lCounts := make([]*types.LicenseCount, len(licenses))
+
+	for i, l := range licenses {
+		lCounts[i] = &types.LicenseCount{
+			License:  l.License,
+			Name:     spdx.Name(l.License),
+			Packages: l.Packages,
+		}
+	}
+
+	helper.WriteResponseValueOK(w, r, lCounts)
+}
+
 // SinglePackageVersionsGET returns the version histories of a single package.
 // Request: GET /versions/:packageName
 func SinglePackageVersionsGET(w http.ResponseWriter, r *http.Request) {
@@ -335,20 +395,661 @@ func SinglePackageVersionsGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for i, v := range *vHistory.Versions {
-		pvh.Versions[i] = types.PackageVersion{
-			Version:    v.Version,
-			ReleasedAt: v.ReleasedAT,
-		}
+		pvh.Versions[i] = packageVersion(v)
 	}
 
 	helper.WriteResponseValueOK(w, r, pvh)
 }
 
+// SinglePackageVersionGET returns a single version of a package along with its integrity
+// and go.mod file.
+// Request: GET /packages/:packageName/versions/:version
+func SinglePackageVersionGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	pv := packageVersion(sv)
+
+	commands, err := pkg.Commands(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+	pv.Commands = commands
+
+	gm, err := pkg.GoMod(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+	if gm != nil {
+		pv.GoMod = packageGoModule(gm)
+	}
+
+	diagnostics, err := pkg.BuildDiagnostics(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+	pv.BuildDiagnostics = diagnostics
+
+	helper.WriteResponseValueOK(w, r, &pv)
+}
+
+// SinglePackageVersionAPIGET returns the exported API of a package version.
+// Request: GET /packages/:packageName/versions/:version/api
+func SinglePackageVersionAPIGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	symbols, err := pkg.Symbols(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pAPI := &types.PackageAPI{
+		Name:    mux.Vars(r)["packageName"],
+		Version: sv.Version,
+		Symbols: make([]*types.PackageSymbol, len(symbols)),
+	}
+
+	for i, s := range symbols {
+		pAPI.Symbols[i] = packageSymbol(s)
+	}
+
+	helper.WriteResponseValueOK(w, r, pAPI)
+}
+
+// SinglePackageVersionDocsGET returns the documentation generated from the Go sources of
+// a package version, as JSON or as an HTML page.
+// Request: GET /packages/:packageName/versions/:version/docs
+// As HTML page: GET /packages/:packageName/versions/:version/docs?format=html
+func SinglePackageVersionDocsGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if !str.IsEmpty(format) && format != "json" && format != "html" {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Format must be one of json, html")
+		return
+	}
+
+	goDocs, err := pkg.Docs(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	symbols, err := pkg.Symbols(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pDocs := &types.PackageDocs{
+		Name:     mux.Vars(r)["packageName"],
+		Version:  sv.Version,
+		Packages: make([]*types.PackageDoc, len(goDocs)),
+	}
+
+	pkgDocs := map[string]*types.PackageDoc{}
+	for i, d := range goDocs {
+		pd := &types.PackageDoc{
+			ImportPath: d.ImportPath,
+			Name:       d.Name,
+			Synopsis:   d.Synopsis,
+			Overview:   d.Doc,
+			Index:      []string{},
+			Symbols:    []*types.PackageSymbol{},
+			Examples:   make([]*types.PackageExample, len(d.Examples)),
+		}
+
+		for j, e := range d.Examples {
+			pd.Examples[j] = &types.PackageExample{
+				Name:   e.Name,
+				Suffix: e.Suffix,
+				Doc:    e.Doc,
+				Code:   e.Code,
+				Output: e.Output,
+			}
+		}
+
+		pDocs.Packages[i] = pd
+		pkgDocs[d.ImportPath] = pd
+	}
+
+	for _, s := range symbols {
+		if pd, ok := pkgDocs[s.ImportPath]; ok {
+			pd.Symbols = append(pd.Symbols, packageSymbol(s))
+		}
+	}
+
+	for _, pd := range pDocs.Packages {
+		docs.SortSymbols(pd.Symbols)
+		for _, s := range pd.Symbols {
+			pd.Index = append(pd.Index, docs.Heading(s))
+		}
+	}
+
+	if format != "html" {
+		helper.WriteResponseValueOK(w, r, pDocs)
+		return
+	}
+
+	var buff bytes.Buffer
+	err = docs.RenderHTML(&buff, pDocs)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "", sv.ReleasedAT, bytes.NewReader(buff.Bytes()))
+}
+
+// SinglePackageVersionChangelogGET returns the release notes of a package version, which
+// are extracted from the section of the version in the CHANGELOG or HISTORY file.
+// Request: GET /packages/:packageName/versions/:version/changelog
+func SinglePackageVersionChangelogGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	cl, err := pkg.VersionChangelog(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if cl == nil {
+		errorCtrl.Error(w, r, http.StatusNotFound, fmt.Sprintf("Version %s has no changelog", sv.Version))
+		return
+	}
+
+	if cl.Notes == nil {
+		errorCtrl.Error(w, r, http.StatusNotFound, fmt.Sprintf("The changelog %s has no release notes for version %s", cl.FileName, sv.Version))
+		return
+	}
+
+	pcl := &types.PackageChangelog{
+		Name:     mux.Vars(r)["packageName"],
+		Version:  sv.Version,
+		FileName: cl.FileName,
+		Date:     cl.Notes.Date,
+		Notes:    cl.Notes.Notes,
+	}
+
+	helper.WriteResponseValueOK(w, r, pcl)
+}
+
+// SinglePackageVersionFilesGET returns the file tree of a package version with the sizes and modes.
+// Request: GET /packages/:packageName/versions/:version/files
+func SinglePackageVersionFilesGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	files, err := pkg.Files(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pFiles := &types.PackageFiles{
+		Name:    mux.Vars(r)["packageName"],
+		Version: sv.Version,
+		Files:   packageFileTree(files),
+	}
+
+	helper.WriteResponseValueOK(w, r, pFiles)
+}
+
+// SinglePackageVersionFileGET returns the raw content of a file of a package version, streamed
+// from the stored archive. The content type is sniffed from the content, the HTML is
+// served as plain text since the files are uploaded by the package authors.
+// The SHA-256 digest of the file is used as the ETag, and the range requests are supported.
+// Request: GET /packages/:packageName/versions/:version/files/:path
+func SinglePackageVersionFileGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	if sv.Status != constants.VersionStatusCommitted {
+		errorCtrl.Error(w, r, http.StatusNotFound, fmt.Sprintf("Version %s is not published yet", sv.Version))
+		return
+	}
+
+	vars := mux.Vars(r)
+	f, err := pkg.File(sv.PackageID, sv.Version, vars["path"])
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if f == nil {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	content, err := pkg.OpenFileContent(sv.PackageName, sv.Version, f)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+	defer content.Close()
+
+	head, err := content.Peek()
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	contentType := http.DetectContentType(head)
+	if strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(contentType, "text/xml") {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	headers := w.Header()
+	headers.Set("Content-Type", contentType)
+	headers.Set("X-Content-Type-Options", "nosniff")
+	headers.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	headers.Set("ETag", fmt.Sprintf("\"%s\"", f.SHA256))
+
+	// The content is streamed from the archive, so only the ranges in ascending order can be served.
+	http.ServeContent(w, r, "", sv.ReleasedAT, content)
+}
+
+// SinglePackageCompareGET compares two versions of a package, i.e. their exported APIs,
+// files and metadata. The unified diffs of the text files are included if both versions
+// are published, within the size limits.
+// Request: GET /packages/:packageName/compare/:from...:to
+// Only the files under a path: GET /packages/:packageName/compare/:from...:to?path=cmd
+func SinglePackageCompareGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	pathFilter := strings.Trim(strings.TrimSpace(r.URL.Query().Get("path")), "/")
+	if !str.IsEmpty(pathFilter) {
+		pathFilter = path.Clean(pathFilter)
+		if pathFilter == ".." || strings.HasPrefix(pathFilter, "../") {
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Path must not point outside of the package")
+			return
+		}
+	}
+
+	from := findVersion(w, r, vars["packageName"], vars["from"])
+	if from == nil {
+		return
+	}
+
+	to := findVersion(w, r, vars["packageName"], vars["to"])
+	if to == nil {
+		return
+	}
+
+	diff, err := pkg.CompareVersions(from.PackageID, from.Version, to.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pc := &types.PackageComparison{
+		Name:    from.PackageName,
+		From:    from.Version,
+		To:      to.Version,
+		Change:  diff.Change,
+		Added:   make([]*types.PackageSymbol, len(diff.Added)),
+		Removed: make([]*types.PackageSymbol, len(diff.Removed)),
+		Changed: make([]*types.PackageSymbolChange, len(diff.Changed)),
+	}
+
+	for i, s := range diff.Added {
+		pc.Added[i] = packageSymbol(s)
+	}
+
+	for i, s := range diff.Removed {
+		pc.Removed[i] = packageSymbol(s)
+	}
+
+	for i, c := range diff.Changed {
+		pc.Changed[i] = &types.PackageSymbolChange{
+			ImportPath:   c.New.ImportPath,
+			Receiver:     c.New.Receiver,
+			Name:         c.New.Name,
+			OldKind:      c.Old.Kind,
+			NewKind:      c.New.Kind,
+			OldSignature: c.Old.Signature,
+			NewSignature: c.New.Signature,
+		}
+	}
+
+	withContent := from.Status == constants.VersionStatusCommitted && to.Status == constants.VersionStatusCommitted
+	fd, err := pkg.DiffFiles(from.PackageName, from.PackageID, from.Version, to.Version, pathFilter, withContent)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pc.Files = make([]*types.PackageFileChange, len(fd.Changes))
+	pc.FilesTruncated = fd.Truncated
+	for i, c := range fd.Changes {
+		pc.Files[i] = &types.PackageFileChange{
+			Path:        c.Path,
+			Status:      c.Status,
+			OldSize:     c.OldSize,
+			NewSize:     c.NewSize,
+			Binary:      c.Binary,
+			Diff:        c.Diff,
+			DiffOmitted: c.DiffOmitted,
+		}
+	}
+
+	fromMeta, err := pkg.VersionMeta(from.PackageID, from.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	toMeta, err := pkg.VersionMeta(to.PackageID, to.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pc.Metadata = []*types.PackageMetaChange{}
+	if fromMeta != nil && toMeta != nil {
+		for _, c := range pkg.DiffMeta(fromMeta, toMeta) {
+			pc.Metadata = append(pc.Metadata, &types.PackageMetaChange{
+				Field: c.Field,
+				Old:   c.Old,
+				New:   c.New,
+			})
+		}
+	}
+
+	helper.WriteResponseValueOK(w, r, pc)
+}
+
+// SinglePackageVersionModGET returns the exact go.mod file of a package version.
+// Request: GET /packages/:packageName/@v/:version.mod
+func SinglePackageVersionModGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	gm, err := pkg.GoMod(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if gm == nil {
+		errorCtrl.Error(w, r, http.StatusNotFound, fmt.Sprintf("Version %s has no go.mod file", sv.Version))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, "", sv.ReleasedAT, bytes.NewReader(gm.Content))
+}
+
+// SinglePackageVersionDependenciesGET returns the declared dependencies of a package version,
+// along with the packages imported by its Go sources.
+// Request: GET /packages/:packageName/versions/:version/dependencies
+// With the resolved transitive dependency graph: GET /packages/:packageName/versions/:version/dependencies?transitive=true
+func SinglePackageVersionDependenciesGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	deps, err := pkg.Dependencies(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	imports, err := pkg.Imports(sv.PackageID, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pDeps := &types.PackageDependencies{
+		Name:         inputPkgName,
+		Version:      sv.Version,
+		Dependencies: packageDependencies(deps),
+		Imports:      packageImports(imports),
+	}
+
+	transitive := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("transitive")))
+	if transitive == "1" || transitive == "true" {
+		graph, err := pkg.ResolveDependencies(inputPkgName, sv.Version, deps)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		pDeps.Resolved = make([]*types.ResolvedPackageDependency, len(graph.Resolved))
+		for i, rd := range graph.Resolved {
+			pDeps.Resolved[i] = &types.ResolvedPackageDependency{
+				Name:         rd.Name,
+				Version:      rd.Version,
+				Dependencies: packageDependencies(rd.Dependencies),
+			}
+		}
+
+		for _, ud := range graph.Unresolved {
+			pDeps.Unresolved = append(pDeps.Unresolved, &types.UnresolvedPackageDependency{
+				Name:       ud.Name,
+				Constraint: ud.Constraint,
+				RequiredBy: ud.RequiredBy,
+			})
+		}
+
+		pDeps.Truncated = graph.Truncated
+	}
+
+	helper.WriteResponseValueOK(w, r, pDeps)
+}
+
+// SinglePackageDependentsGET returns the packages whose latest version depends on a package.
+// Request: GET /packages/:packageName/dependents?sort=downloads&order=desc&page=1&per_page=10
+// Sorting can be performed on:
+// 1. downloads
+// 2. name
+func SinglePackageDependentsGET(w http.ResponseWriter, r *http.Request) {
+	inputPkgName := mux.Vars(r)["packageName"]
+	params := r.URL.Query()
+
+	var (
+		pageStr    = params.Get("page")
+		perPageStr = params.Get("per_page")
+		sort       = params.Get("sort")
+		order      = params.Get("order")
+	)
+
+	pkgRows, err := pkg.Query("name = ?", "id ASC", "1", "", inputPkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if len(pkgRows) == 0 {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	page, err := strconv.ParseUint(pageStr, 10, 64)
+	if err != nil {
+		page = 1
+	}
+
+	perPage, err := strconv.ParseUint(perPageStr, 10, 64)
+	if err != nil {
+		perPage = uint64(constants.PackagesQueryMaxPageSize)
+	}
+
+	pc := helper.PaginationConfig{
+		Page:         page,
+		PerPageCount: perPage,
+	}
+
+	sc := helper.SortingConfig{
+		SortBy: sort,
+		Order:  order,
+	}
+
+	pkgRows, err = pkg.Dependents(pkgRows[0].Name, &pc, &sc)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	pkgs := make([]*types.Package, len(pkgRows))
+
+	for i, pr := range pkgRows {
+		pkgs[i] = &types.Package{
+			Name:             pr.Name,
+			ID:               pr.ID,
+			Desc:             pr.Description,
+			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
+			Version:          pr.LatestVersion,
+			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
+			PublishedAt:      pr.PublishedAt,
+			UpdatedAt:        pr.LastReleasedAt,
+			License:          pr.License,
+			Homepage:         pr.HomepageURL,
+			RepositoryURL:    pr.RepositoryURL,
+			DocumentationURL: pr.DocumentationURL,
+			BugsURL:          pr.BugsURL,
+			Engines: types.Engines{
+				Go: pr.EnginesGO,
+			},
+			Os: listOsNames(pr.OS),
+		}
+	}
+
+	helper.WriteResponseValueOK(w, r, pkgs)
+}
+
+// SinglePackageVersionArchiveGET downloads the archive of a package version.
+// The recorded digests are sent in the Digest header and the SHA-256 digest
+// is used as the ETag, so that the clients can verify the downloaded archive.
+// Request: GET /packages/:packageName/versions/:version/archive
+func SinglePackageVersionArchiveGET(w http.ResponseWriter, r *http.Request) {
+	sv := requestedVersion(w, r)
+	if sv == nil {
+		return
+	}
+
+	if sv.Status != constants.VersionStatusCommitted {
+		errorCtrl.Error(w, r, http.StatusNotFound, fmt.Sprintf("Version %s is not published yet", sv.Version))
+		return
+	}
+
+	f, err := storage.OpenArchive(sv.PackageName, sv.Version)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error404(w, r)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	headers := w.Header()
+	if !str.IsEmpty(sv.SHA256) {
+		digest, err := digestHeader(sv.SHA256, sv.SHA512)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+		headers.Set("Digest", digest)
+		headers.Set("ETag", fmt.Sprintf("\"%s\"", sv.SHA256))
+	}
+	headers.Set("Content-Type", "application/gzip")
+	headers.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s.tar.gz\"", sv.PackageName, sv.Version))
+
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+}
+
+// digestHeader creates the value of the Digest header from the hex encoded digests.
+func digestHeader(sha256Hex, sha512Hex string) (value string, err error) {
+	sha256Bytes, err := hex.DecodeString(sha256Hex)
+	if err != nil {
+		return
+	}
+
+	sha512Bytes, err := hex.DecodeString(sha512Hex)
+	if err != nil {
+		return
+	}
+
+	value = fmt.Sprintf("SHA-256=%s,SHA-512=%s", base64.StdEncoding.EncodeToString(sha256Bytes), base64.StdEncoding.EncodeToString(sha512Bytes))
+
+	return value, nil
+}
+
 // SinglePackageReadmeGET returns the content of README file.
+// The format is one of "base64" (default), "raw" or "html", the HTML is rendered from
+// the Markdown, sanitized, and its relative links point to the files of the version.
 // Request: GET /packages/:packageName/readme
 // For a specific version: GET /packages/:packageName/readme?v=1.0.2
+// As HTML: GET /packages/:packageName/readme?format=html
 func SinglePackageReadmeGET(w http.ResponseWriter, r *http.Request) {
 	inputPkgName := mux.Vars(r)["packageName"]
+
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if str.IsEmpty(format) {
+		format = "base64"
+	}
+	if format != "base64" && format != "raw" && format != "html" {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Format must be one of base64, raw, html")
+		return
+	}
+
 	whereClause := "name = ?"
 	sortBy := "id ASC"
 	limit := "1"
@@ -389,6 +1090,20 @@ func SinglePackageReadmeGET(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if format == "html" {
+		filesURL := fmt.Sprintf("/v1/packages/%s/versions/%s/files/", pkgRows[0].Name, inputVersion)
+		rendered, err := pkg.ReadmeHTML(pkgRows[0].ID, inputVersion, filesURL)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(rendered))
+		return
+	}
+
 	readmeData, err := pkg.Readme(pkgRows[0].ID, inputVersion)
 	if err != nil {
 		log.Error("Error %s", err)
@@ -396,6 +1111,12 @@ func SinglePackageReadmeGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if format == "raw" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(readmeData.Raw))
+		return
+	}
+
 	readmeResp := &types.PackageReadme{
 		Name:    readmeData.Name,
 		Version: readmeData.Version,
@@ -405,3 +1126,38 @@ func SinglePackageReadmeGET(w http.ResponseWriter, r *http.Request) {
 
 	helper.WriteResponseValueOK(w, r, readmeResp)
 }
+
+// PackageValidatePOST validates a package of the authenticated user without publishing it.
+// It runs the same checks as publishing and responds with all the errors and warnings
+// along with the normalized metadata which would be stored.
+// Request: POST /validate
+// To report the unknown or mistyped metadata fields as errors: POST /validate?strict=true
+func PackageValidatePOST(w http.ResponseWriter, r *http.Request) {
+	ur, err := authUser(r.Header.Get("Authorization"))
+
+	if err != nil {
+		switch err {
+		case constants.ErrInternalServer:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		default:
+			errorCtrl.Error(w, r, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+	}
+
+	if ur == nil {
+		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	tmpFile := receivePackageData(w, r, ur)
+	if tmpFile == nil {
+		return
+	}
+	defer os.RemoveAll(tmpFile.Name())
+	defer tmpFile.Close()
+
+	validatePackageData(w, r, tmpFile, ur)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,14 +13,15 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
-	yaml "gopkg.in/yaml.v2"
+	"github.com/pkg/errors"
 	"gopx.io/gopx-api/api/v1/constants"
 	"gopx.io/gopx-api/api/v1/controller/helper"
+	"gopx.io/gopx-api/api/v1/controller/job"
 	"gopx.io/gopx-api/api/v1/controller/pkg"
 	"gopx.io/gopx-api/api/v1/controller/user"
 	"gopx.io/gopx-api/api/v1/types"
 	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
-	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-common/arr"
 	"gopx.io/gopx-common/log"
 	"gopx.io/gopx-common/misc"
 	"gopx.io/gopx-common/str"
@@ -113,6 +113,18 @@ func SingleUserGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(userRows) == 0 {
+		newUsername, err := user.RenamedTo(inputUsername)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		if !str.IsEmpty(newUsername) {
+			helper.WriteRedirect(w, r, renamedUserURL(r, inputUsername, newUsername), http.StatusMovedPermanently)
+			return
+		}
+
 		errorCtrl.Error404(w, r)
 		return
 	}
@@ -354,6 +366,101 @@ func CurrentUserPATCH(w http.ResponseWriter, r *http.Request) {
 	helper.WriteResponseValueOK(w, r, user)
 }
 
+// CurrentUserUsernamePATCH changes the username of the authenticated user.
+// The old '/users/:username' routes are redirected to the new username and
+// the owner info of the user's packages are re-synced to the vcs registry.
+// Request: PATCH /user/username
+func CurrentUserUsernamePATCH(w http.ResponseWriter, r *http.Request) {
+	ur, err := authUser(r.Header.Get("Authorization"))
+
+	if err != nil {
+		switch err {
+		case constants.ErrInternalServer:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		default:
+			errorCtrl.Error(w, r, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+	}
+
+	if ur == nil {
+		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	inputData := types.UsernameMutation{}
+	err = json.NewDecoder(r.Body).Decode(&inputData)
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing JSON data")
+		return
+	}
+
+	newUsername := strings.TrimSpace(inputData.Username)
+	err = helper.ValidateUsername(newUsername)
+	if err != nil {
+		switch err {
+		case constants.ErrInternalServer:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		default:
+			errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if newUsername == ur.Username {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "New username must be different from the current one")
+		return
+	}
+
+	ur, err = user.ChangeUsername(ur.ID, newUsername)
+	if err != nil {
+		switch err {
+		case constants.ErrUsernameTaken:
+			errorCtrl.Error(w, r, http.StatusConflict, err.Error())
+			return
+		case constants.ErrUsernameChangeCooldown:
+			errorCtrl.Error(w, r, http.StatusTooManyRequests, err.Error())
+			return
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+	}
+
+	// The username is already changed at this point, so a failure to record
+	// the sync of the vcs registry must not fail the request.
+	err = pkg.SyncPackageOwner(ur)
+	if err != nil {
+		log.Error("Error %s", err)
+	}
+
+	user := &types.User{
+		Username:      ur.Username,
+		ID:            ur.ID,
+		Name:          ur.Name,
+		Email:         ur.Email,
+		JoinedAt:      ur.JoinedAt,
+		AvatarURL:     ur.Avatar,
+		Blog:          ur.URL,
+		Organization:  ur.Organization,
+		Location:      ur.Location,
+		PackagesCount: ur.PackagesCount,
+		Social: types.UserSocialAccounts{
+			Github:        ur.Github,
+			Twitter:       ur.Twitter,
+			StackOverflow: ur.StackOverflow,
+			LinkedIn:      ur.LinkedIn,
+		},
+	}
+
+	helper.WriteResponseValueOK(w, r, user)
+}
+
 // SingleUserPackagesGET returns the list of public packages of a single user.
 // Request: GET /users/:username/packages?sort=downloads,id&order=desc&page=1&per_page=10
 // Sorting can be performed on:
@@ -362,6 +469,7 @@ func CurrentUserPATCH(w http.ResponseWriter, r *http.Request) {
 // 3. updated
 // 4. name
 // 5. id
+// 6. dependents
 func SingleUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 	inputUsername := mux.Vars(r)["username"]
 
@@ -373,6 +481,18 @@ func SingleUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(userRows) == 0 {
+		newUsername, err := user.RenamedTo(inputUsername)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		}
+
+		if !str.IsEmpty(newUsername) {
+			helper.WriteRedirect(w, r, renamedUserURL(r, inputUsername, newUsername), http.StatusMovedPermanently)
+			return
+		}
+
 		errorCtrl.Error404(w, r)
 		return
 	}
@@ -425,8 +545,10 @@ func SingleUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 			ID:               pr.ID,
 			Desc:             pr.Description,
 			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
 			Version:          pr.LatestVersion,
 			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
 			PublishedAt:      pr.PublishedAt,
 			UpdatedAt:        pr.LastReleasedAt,
 			License:          pr.License,
@@ -452,6 +574,7 @@ func SingleUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 // 3. updated
 // 4. name
 // 5. id
+// 6. dependents
 func CurrentUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 	ur, err := authUser(r.Header.Get("Authorization"))
 
@@ -520,8 +643,10 @@ func CurrentUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 			ID:               pr.ID,
 			Desc:             pr.Description,
 			Owner:            pr.OwnerUsername,
+			Status:           pr.Status,
 			Version:          pr.LatestVersion,
 			Downloads:        pr.Downloads,
+			DependentsCount:  pr.DependentsCount,
 			PublishedAt:      pr.PublishedAt,
 			UpdatedAt:        pr.LastReleasedAt,
 			License:          pr.License,
@@ -541,6 +666,11 @@ func CurrentUserPackagesGET(w http.ResponseWriter, r *http.Request) {
 
 // CurrentUserPackagesPOST registers a new package of a authenticated user.
 // Request: POST /user/packages
+// To only validate the package without publishing: POST /user/packages?dry_run=true
+// To reject the unknown or mistyped metadata fields instead of warning: POST /user/packages?strict=true
+// To publish asynchronously: POST /user/packages?async=true
+// or with the 'Prefer: respond-async' header. It responds with a publish job
+// which can be polled at /publish-jobs/:jobID.
 func CurrentUserPackagesPOST(w http.ResponseWriter, r *http.Request) {
 	ur, err := authUser(r.Header.Get("Authorization"))
 
@@ -561,159 +691,188 @@ func CurrentUserPackagesPOST(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mr, err := r.MultipartReader()
-	if err != nil {
-		errorCtrl.Error(w, r, http.StatusBadRequest, "Content-Type must be multipart/form-data")
+	tmpFile := receivePackageData(w, r, ur)
+	if tmpFile == nil {
+		return
+	}
+	keepTmpFile := false
+	defer func() {
+		if !keepTmpFile {
+			os.RemoveAll(tmpFile.Name())
+		}
+	}()
+	defer tmpFile.Close()
+
+	if isDryRunRequest(r) {
+		validatePackageData(w, r, tmpFile, ur)
 		return
 	}
 
-	tmpFile, err := ioutil.TempFile("", constants.TempFileNamePrefixForPackageRegister)
+	if isAsyncRequest(r) {
+		pj, err := job.Submit(tmpFile.Name(), ur, isStrictRequest(r))
+		if err != nil {
+			switch err {
+			case job.ErrQueueFull:
+				errorCtrl.Error(w, r, http.StatusServiceUnavailable, err.Error())
+				return
+			default:
+				log.Error("Error %s", err)
+				errorCtrl.Error500(w, r)
+				return
+			}
+		}
+		keepTmpFile = true
+
+		pjData := &types.PublishJob{
+			ID:        pj.ID,
+			Status:    pj.Status,
+			Stage:     pj.Stage,
+			CreatedAt: pj.CreatedAt,
+			UpdatedAt: pj.UpdatedAt,
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/v1/publish-jobs/%s", pj.ID))
+		helper.WriteResponseValue(w, r, pjData, http.StatusAccepted)
+		return
+	}
+
+	_, err = tmpFile.Seek(0, 0)
 	if err != nil {
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
 		return
 	}
-	defer os.RemoveAll(tmpFile.Name())
-	defer tmpFile.Close()
 
-	ok, err := readPackageData(mr, tmpFile)
+	result, err := pkg.Publish(tmpFile, ur, isStrictRequest(r), nil)
 	if err != nil {
+		if pErr, ok := err.(*pkg.PublishError); ok {
+			writePublishError(w, r, pErr)
+			return
+		}
+
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
 		return
 	}
 
-	if !ok {
-		errorCtrl.Error(w, r, http.StatusBadRequest, "Package data not found with param name data")
-		return
+	// The vcs registry has not stored the version yet, the reconciler
+	// completes the publish in background.
+	statusCode := http.StatusCreated
+	if result.Status != constants.VersionStatusCommitted {
+		statusCode = http.StatusAccepted
 	}
 
-	_, err = tmpFile.Seek(0, 0)
+	helper.WriteResponseValue(w, r, publishedPackage(result.Package, result.Version, result.Status, result.Warnings), statusCode)
+}
+
+// receivePackageData reads the uploaded package data of the user to a temp file.
+// On failure, it writes the error response and returns nil, otherwise the caller
+// is responsible for removing the temp file.
+func receivePackageData(w http.ResponseWriter, r *http.Request, ur *user.QueryRow) (tmpFile *os.File) {
+	maxSize := pkg.MaxDataSize(ur)
+	maxBodySize := maxSize + constants.PackageUploadOverheadMaxSize
+	if r.ContentLength > maxBodySize {
+		writePackageDataTooLarge(w, r, maxSize)
+		return nil
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Content-Type must be multipart/form-data")
+		return nil
+	}
+
+	tmpFile, err = ioutil.TempFile("", constants.TempFileNamePrefixForPackageRegister)
 	if err != nil {
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
-		return
+		return nil
 	}
 
-	var pkgMetaBuff bytes.Buffer
-	ok, idx, err := fs.ReadEntryTarGz(tmpFile, &pkgMetaBuff, constants.PackageMetaFileNames)
-	if err != nil {
-		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid data or exceeds maximum allowed size %dMB", constants.PackageDataMaxSize/(1024*1024)))
-		return
+	ok, err := readPackageData(mr, tmpFile, maxSize)
+	if err != nil || !ok {
+		tmpFile.Close()
+		os.RemoveAll(tmpFile.Name())
 	}
 
-	_, err = tmpFile.Seek(0, 0)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if err == errPackageDataTooLarge || errors.As(err, &maxBytesErr) {
+			writePackageDataTooLarge(w, r, maxSize)
+			return nil
+		}
+
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
-		return
+		return nil
 	}
 
 	if !ok {
-		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("The meta file %s not found in package contents", strings.Join(constants.PackageMetaFileNames, " or ")))
-		return
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Package data not found with param name data")
+		return nil
 	}
 
-	metaFileName := constants.PackageMetaFileNames[idx]
-	meta := types.PackageMetaData{}
-	if metaFileName == "gopx.json" {
-		err = json.NewDecoder(&pkgMetaBuff).Decode(&meta)
-		if err != nil {
-			errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Problems parsing %s file", metaFileName))
-			return
-		}
-	} else {
-		err = yaml.NewDecoder(&pkgMetaBuff).Decode(&meta)
-		if err != nil {
-			errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Problems parsing %s file", metaFileName))
-			return
-		}
-	}
+	return tmpFile
+}
 
-	err = pkg.SanitizePackageMeta(&meta)
+// validatePackageData validates the uploaded package data without publishing it, it
+// responds with "422 Unprocessable Entity" along with the validation if there is any error.
+func validatePackageData(w http.ResponseWriter, r *http.Request, tmpFile *os.File, ur *user.QueryRow) {
+	_, err := tmpFile.Seek(0, 0)
 	if err != nil {
-		switch err {
-		case constants.ErrInternalServer:
-			log.Error("Error %s", err)
-			errorCtrl.Error500(w, r)
-			return
-		default:
-			errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
-			return
-		}
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
 	}
 
-	pkgRows, err := pkg.Query("name = ?", "id ASC", "1", "", meta.Name)
+	v, err := pkg.Validate(tmpFile, ur, isStrictRequest(r))
 	if err != nil {
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
 		return
 	}
 
-	var fPkg *pkg.QueryRow
-
-	if len(pkgRows) < 1 {
-		iPkg, err := pkg.InsertNew(&meta, tmpFile, ur)
-		if err != nil {
-			log.Error("Error %s", err)
-			errorCtrl.Error500(w, r)
-			return
-		}
-		fPkg = iPkg
-	} else {
-		pkgRow := pkgRows[0]
-
-		if pkgRow.OwnerUsername != ur.Username {
-			errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
-			return
-		}
+	pv := &types.PackageValidation{
+		Valid:    v.Valid(),
+		Errors:   v.Errors,
+		Warnings: v.Warnings,
+		Entries:  v.Entries,
+		Meta:     v.Meta,
+	}
 
-		ok, err := helper.IsSameVersion(pkgRow.LatestVersion, meta.Version)
-		if err != nil {
-			log.Error("Error %s", err)
-			errorCtrl.Error500(w, r)
-			return
-		}
+	statusCode := http.StatusOK
+	if !pv.Valid {
+		statusCode = http.StatusUnprocessableEntity
+	}
 
-		if ok {
-			errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Package version %s already exists", meta.Version))
-			return
-		}
+	helper.WriteResponseValue(w, r, pv, statusCode)
+}
 
-		uPkg, err := pkg.MakeNewRelease(pkgRow.ID, &meta, tmpFile, ur)
-		if err != nil {
-			log.Error("Error %s", err)
-			errorCtrl.Error500(w, r)
-			return
-		}
+// writePublishError responds with a publish failure, all the errors, warnings and the
+// offending archive entries are sent in the details like the dry-run and the job do.
+func writePublishError(w http.ResponseWriter, r *http.Request, pErr *pkg.PublishError) {
+	if len(pErr.Errors) == 0 && len(pErr.Warnings) == 0 && len(pErr.Entries) == 0 {
+		errorCtrl.Error(w, r, pErr.StatusCode, pErr.Message)
+		return
+	}
 
-		fPkg = uPkg
-	}
-
-	pkgData := &types.Package{
-		Name:             fPkg.Name,
-		ID:               fPkg.ID,
-		Desc:             fPkg.Description,
-		Owner:            fPkg.OwnerUsername,
-		Version:          fPkg.LatestVersion,
-		Downloads:        fPkg.Downloads,
-		PublishedAt:      fPkg.PublishedAt,
-		UpdatedAt:        fPkg.LastReleasedAt,
-		License:          fPkg.License,
-		Homepage:         fPkg.HomepageURL,
-		RepositoryURL:    fPkg.RepositoryURL,
-		DocumentationURL: fPkg.DocumentationURL,
-		BugsURL:          fPkg.BugsURL,
-		Engines: types.Engines{
-			Go: fPkg.EnginesGO,
-		},
-		Os: listOsNames(fPkg.OS),
+	details := &types.PublishErrorDetails{
+		Errors:   pErr.Errors,
+		Warnings: pErr.Warnings,
+		Entries:  pErr.Entries,
+	}
+	if len(details.Errors) == 0 {
+		details.Errors = []string{pErr.Message}
 	}
 
-	helper.WriteResponseValue(w, r, pkgData, http.StatusCreated)
+	errorCtrl.ErrorDetails(w, r, pErr.StatusCode, pErr.Message, details)
 }
 
-func readPackageData(mr *multipart.Reader, w io.Writer) (ok bool, err error) {
+// errPackageDataTooLarge indicates that the uploaded package data exceeds the allowed size.
+var errPackageDataTooLarge = errors.New("Package data is too large")
+
+func readPackageData(mr *multipart.Reader, w io.Writer, maxSize int64) (ok bool, err error) {
 	for {
 		p, err := mr.NextPart()
 		if err != nil {
@@ -724,7 +883,7 @@ func readPackageData(mr *multipart.Reader, w io.Writer) (ok bool, err error) {
 			}
 		}
 
-		ok, err := readSinglePart(p, w)
+		ok, err := readSinglePart(p, w, maxSize)
 		if err != nil {
 			return false, err
 		}
@@ -737,17 +896,26 @@ func readPackageData(mr *multipart.Reader, w io.Writer) (ok bool, err error) {
 	return false, nil
 }
 
-func readSinglePart(p *multipart.Part, w io.Writer) (ok bool, err error) {
+func readSinglePart(p *multipart.Part, w io.Writer, maxSize int64) (ok bool, err error) {
 	defer p.Close()
 	if p.FormName() == constants.PackageUploadParamName {
-		_, err = io.CopyN(w, p, constants.PackageDataMaxSize)
-		if err == nil || err == io.EOF {
-			ok = true
-			err = nil
+		// Read one byte more than the limit to detect the oversized data
+		// instead of truncating it.
+		n, err := io.CopyN(w, p, maxSize+1)
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		if n > maxSize {
+			return false, errPackageDataTooLarge
 		}
+		ok = true
 	}
 
-	return
+	return ok, nil
+}
+
+func writePackageDataTooLarge(w http.ResponseWriter, r *http.Request, maxSize int64) {
+	errorCtrl.Error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Package data exceeds maximum allowed size %d bytes", maxSize))
 }
 
 // CurrentUserPackagesDELETE deletes a whole package and free up the package name.
@@ -792,12 +960,114 @@ func CurrentUserPackagesDELETE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = pkg.DeletePackage(inputPkgName)
+	if pkgRows[0].Status == constants.PackageStatusDeleting {
+		errorCtrl.Error(w, r, http.StatusConflict, fmt.Sprintf("Package %s is already being deleted", inputPkgName))
+		return
+	}
+
+	status, err := pkg.DeletePackage(inputPkgName)
 	if err != nil {
+		if err == pkg.ErrPackageDeleting {
+			errorCtrl.Error(w, r, http.StatusConflict, fmt.Sprintf("Package %s is already being deleted", inputPkgName))
+			return
+		}
+
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
 		return
 	}
 
+	// The vcs registry has not deleted the package yet, the reconciler
+	// completes the deletion in background.
+	if status != constants.VersionStatusCommitted {
+		pd := &types.PackageDeletion{
+			Name:   inputPkgName,
+			Status: status,
+		}
+		helper.WriteResponseValue(w, r, pd, http.StatusAccepted)
+		return
+	}
+
 	helper.WriteResponse(w, r, nil, http.StatusNoContent)
 }
+
+// CurrentUserPackagesPATCH updates the settings of a package of the authenticated user.
+// The semver policy decides what happens when the version bump of a release is smaller
+// than its API changes require, and the build policy decides what happens when the Go
+// sources of a release do not type-check, both are one of "off", "warn" or "reject".
+// Request: PATCH /user/packages/:packageName
+func CurrentUserPackagesPATCH(w http.ResponseWriter, r *http.Request) {
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	pkgRows, err := pkg.Query("name = ?", "id ASC", "1", "", inputPkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if len(pkgRows) == 0 {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	ur, err := authUser(r.Header.Get("Authorization"))
+
+	if err != nil {
+		switch err {
+		case constants.ErrInternalServer:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return
+		default:
+			errorCtrl.Error(w, r, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+	}
+
+	if ur == nil {
+		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	// Important
+	if ur.Username != pkgRows[0].OwnerUsername {
+		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	inputData := types.PackageMutation{}
+	err = json.NewDecoder(r.Body).Decode(&inputData)
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing JSON data")
+		return
+	}
+
+	if inputData.SemverPolicy != nil && arr.FindStr(constants.SemverPolicies, *inputData.SemverPolicy) == -1 {
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Semver policy must be one of %s", strings.Join(constants.SemverPolicies, ", ")))
+		return
+	}
+
+	if inputData.BuildPolicy != nil && arr.FindStr(constants.BuildPolicies, *inputData.BuildPolicy) == -1 {
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Build policy must be one of %s", strings.Join(constants.BuildPolicies, ", ")))
+		return
+	}
+
+	pr, err := pkg.UpdateInfo(pkgRows[0].ID, &pkg.MutationData{
+		SemverPolicy: inputData.SemverPolicy,
+		BuildPolicy:  inputData.BuildPolicy,
+	})
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	ps := &types.PackageSettings{
+		Name:         pr.Name,
+		SemverPolicy: pr.SemverPolicy,
+		BuildPolicy:  pr.BuildPolicy,
+	}
+
+	helper.WriteResponseValueOK(w, r, ps)
+}
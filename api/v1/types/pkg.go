@@ -6,21 +6,24 @@ import (
 
 // Package represents a single GoPx package.
 type Package struct {
-	Name             string    `json:"name"`
-	ID               uint64    `json:"id"`
-	Desc             string    `json:"desc"`
-	Owner            string    `json:"owner"`
-	Version          string    `json:"version"`
-	Downloads        uint64    `json:"downloads"`
-	PublishedAt      time.Time `json:"publishedAt"`
-	UpdatedAt        time.Time `json:"updatedAt"`
-	License          string    `json:"license"`
-	Homepage         string    `json:"homepage"`
-	RepositoryURL    string    `json:"repositoryURL"`
-	DocumentationURL string    `json:"documentationURL"`
-	BugsURL          string    `json:"bugsURL"`
-	Engines          Engines   `json:"engines"`
-	Os               []string  `json:"os"`
+	Name             string            `json:"name"`
+	ID               uint64            `json:"id"`
+	Desc             string            `json:"desc"`
+	Owner            string            `json:"owner"`
+	Status           string            `json:"status"`
+	Version          string            `json:"version"`
+	Downloads        uint64            `json:"downloads"`
+	DependentsCount  uint64            `json:"dependentsCount"`
+	PublishedAt      time.Time         `json:"publishedAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+	License          string            `json:"license"`
+	Homepage         string            `json:"homepage"`
+	RepositoryURL    string            `json:"repositoryURL"`
+	DocumentationURL string            `json:"documentationURL"`
+	BugsURL          string            `json:"bugsURL"`
+	Engines          Engines           `json:"engines"`
+	Os               []string          `json:"os"`
+	Commands         map[string]string `json:"commands,omitempty"`
 }
 
 // Engines holds package environment dependencies.
@@ -40,6 +43,14 @@ type RegistryDownloads struct {
 	Downloads uint64 `json:"downloads"`
 }
 
+// LicenseCount holds the number of packages under a license expression, the name is
+// set for the known SPDX license identifiers.
+type LicenseCount struct {
+	License  string `json:"license"`
+	Name     string `json:"name,omitempty"`
+	Packages uint64 `json:"packages"`
+}
+
 // PackageVersionHistory holds versions history of a package.
 type PackageVersionHistory struct {
 	Name     string           `json:"name"`
@@ -49,8 +60,77 @@ type PackageVersionHistory struct {
 
 // PackageVersion holds info of a single version.
 type PackageVersion struct {
-	Version    string    `json:"version"`
-	ReleasedAt time.Time `json:"releasedAt"`
+	Version          string            `json:"version"`
+	Status           string            `json:"status"`
+	Integrity        *PackageIntegrity `json:"integrity,omitempty"`
+	BuildStatus      string            `json:"buildStatus,omitempty"`
+	BuildDiagnostics []string          `json:"buildDiagnostics,omitempty"`
+	NotesExcerpt     string            `json:"notesExcerpt,omitempty"`
+	Commands         map[string]string `json:"commands,omitempty"`
+	GoMod            *PackageGoModule  `json:"goMod,omitempty"`
+	ReleasedAt       time.Time         `json:"releasedAt"`
+}
+
+// PackageGoModule holds the go.mod file of a package version.
+type PackageGoModule struct {
+	Path    string                  `json:"path"`
+	Go      string                  `json:"go,omitempty"`
+	Require []*PackageModuleRequire `json:"require"`
+	Replace []*PackageModuleReplace `json:"replace"`
+}
+
+// PackageModuleRequire holds a require directive of go.mod.
+type PackageModuleRequire struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Indirect bool   `json:"indirect,omitempty"`
+}
+
+// PackageModuleReplace holds a replace directive of go.mod.
+type PackageModuleReplace struct {
+	OldPath    string `json:"oldPath"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewPath    string `json:"newPath"`
+	NewVersion string `json:"newVersion,omitempty"`
+}
+
+// PackageIntegrity holds the hex encoded digests of the archive of a package version.
+type PackageIntegrity struct {
+	SHA256 string `json:"sha256"`
+	SHA512 string `json:"sha512"`
+}
+
+// PublishedPackage holds the package data along with the status of
+// the just published version.
+type PublishedPackage struct {
+	*Package
+	PublishedVersion string   `json:"publishedVersion"`
+	PublishStatus    string   `json:"publishStatus"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// PackageDeletion holds the status of a package deletion which is
+// not completed yet.
+type PackageDeletion struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// ArchiveEntryError holds an entry of the package archive which is rejected
+// along with the reason.
+type ArchiveEntryError struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// PackageValidation holds the outcome of validating a package without publishing it,
+// the metadata is the normalized form which would be stored.
+type PackageValidation struct {
+	Valid    bool                 `json:"valid"`
+	Errors   []string             `json:"errors"`
+	Warnings []string             `json:"warnings"`
+	Entries  []*ArchiveEntryError `json:"entries,omitempty"`
+	Meta     *PackageMetaData     `json:"meta,omitempty"`
 }
 
 // PackageMetaData holds the metadata of a gopx package i.e. contents of the gopx.json or gopx.yaml or gopx.yml file.
@@ -66,6 +146,8 @@ type PackageMetaData struct {
 	DocumentationURL string                 `json:"docs" yaml:"docs"`
 	Engines          PackageMetaDataEngines `json:"engines" yaml:"engines"`
 	Os               []string               `json:"os" yaml:"os"`
+	Commands         map[string]string      `json:"commands" yaml:"commands"`
+	Dependencies     map[string]string      `json:"dependencies" yaml:"dependencies"`
 }
 
 // PackageMetaDataEngines holds the engines metadata of a gopx package.
@@ -73,6 +155,184 @@ type PackageMetaDataEngines struct {
 	Go string `json:"go" yaml:"go"`
 }
 
+// PackageDependencies holds the declared dependencies of a package version, the packages
+// actually imported by its Go sources, and optionally the resolved transitive dependency graph.
+type PackageDependencies struct {
+	Name         string                         `json:"name"`
+	Version      string                         `json:"version"`
+	Dependencies []*PackageDependency           `json:"dependencies"`
+	Imports      *PackageImports                `json:"imports"`
+	Resolved     []*ResolvedPackageDependency   `json:"resolved,omitempty"`
+	Unresolved   []*UnresolvedPackageDependency `json:"unresolved,omitempty"`
+	Truncated    bool                           `json:"truncated,omitempty"`
+}
+
+// PackageDependency holds a single declared dependency, the kind is either
+// "gopx" for a GoPx package or "module" for a Go module path.
+type PackageDependency struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+	Kind       string `json:"kind"`
+}
+
+// PackageImports holds the import paths of the Go sources of a package version,
+// grouped by their kinds.
+type PackageImports struct {
+	Standard []string `json:"standard"`
+	GoPx     []string `json:"gopx"`
+	Modules  []string `json:"modules"`
+}
+
+// ResolvedPackageDependency holds a GoPx package version in the resolved dependency graph.
+type ResolvedPackageDependency struct {
+	Name         string               `json:"name"`
+	Version      string               `json:"version"`
+	Dependencies []*PackageDependency `json:"dependencies"`
+}
+
+// UnresolvedPackageDependency holds a GoPx dependency which no published version satisfies.
+type UnresolvedPackageDependency struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+	RequiredBy string `json:"requiredBy"`
+}
+
+// PackageAPI holds the exported symbols of the Go sources of a package version.
+type PackageAPI struct {
+	Name    string           `json:"name"`
+	Version string           `json:"version"`
+	Symbols []*PackageSymbol `json:"symbols"`
+}
+
+// PackageSymbol holds a single exported symbol, the kind is one of "const", "var",
+// "func", "type" or "method".
+type PackageSymbol struct {
+	ImportPath string `json:"importPath"`
+	Kind       string `json:"kind"`
+	Receiver   string `json:"receiver,omitempty"`
+	Name       string `json:"name"`
+	Signature  string `json:"signature"`
+	Doc        string `json:"doc,omitempty"`
+}
+
+// PackageFiles holds the file tree of a package version.
+type PackageFiles struct {
+	Name    string             `json:"name"`
+	Version string             `json:"version"`
+	Files   []*PackageFileNode `json:"files"`
+}
+
+// PackageFileNode holds a file or a directory of a package version, the type is one of
+// "file" or "dir". The size of a directory is the total size of the files under it,
+// and the mode is the octal permission bits of a file.
+type PackageFileNode struct {
+	Name     string             `json:"name"`
+	Path     string             `json:"path"`
+	Type     string             `json:"type"`
+	Size     int64              `json:"size"`
+	Mode     string             `json:"mode,omitempty"`
+	SHA256   string             `json:"sha256,omitempty"`
+	Children []*PackageFileNode `json:"children,omitempty"`
+}
+
+// PackageDocs holds the documentation of the Go packages of a package version.
+type PackageDocs struct {
+	Name     string        `json:"name"`
+	Version  string        `json:"version"`
+	Packages []*PackageDoc `json:"packages"`
+}
+
+// PackageDoc holds the documentation of a single Go package, the index holds
+// the headings of the symbols in order.
+type PackageDoc struct {
+	ImportPath string            `json:"importPath"`
+	Name       string            `json:"name"`
+	Synopsis   string            `json:"synopsis"`
+	Overview   string            `json:"overview"`
+	Index      []string          `json:"index"`
+	Symbols    []*PackageSymbol  `json:"symbols"`
+	Examples   []*PackageExample `json:"examples"`
+}
+
+// PackageExample holds an example of a Go package, the name is empty for the package
+// examples, the name of the function or type, or <type>_<method> for the methods.
+type PackageExample struct {
+	Name   string `json:"name"`
+	Suffix string `json:"suffix,omitempty"`
+	Doc    string `json:"doc,omitempty"`
+	Code   string `json:"code"`
+	Output string `json:"output,omitempty"`
+}
+
+// PackageMutation holds package settings mutation data.
+type PackageMutation struct {
+	SemverPolicy *string `json:"semverPolicy"`
+	BuildPolicy  *string `json:"buildPolicy"`
+}
+
+// PackageSettings holds the settings of a package.
+type PackageSettings struct {
+	Name         string `json:"name"`
+	SemverPolicy string `json:"semverPolicy"`
+	BuildPolicy  string `json:"buildPolicy"`
+}
+
+// PackageComparison holds the differences between the exported APIs of two versions
+// of a package, change is the minimum version bump the differences require.
+type PackageComparison struct {
+	Name           string                 `json:"name"`
+	From           string                 `json:"from"`
+	To             string                 `json:"to"`
+	Change         string                 `json:"change"`
+	Added          []*PackageSymbol       `json:"added"`
+	Removed        []*PackageSymbol       `json:"removed"`
+	Changed        []*PackageSymbolChange `json:"changed"`
+	Files          []*PackageFileChange   `json:"files"`
+	FilesTruncated bool                   `json:"filesTruncated,omitempty"`
+	Metadata       []*PackageMetaChange   `json:"metadata"`
+}
+
+// PackageFileChange holds a file which differs between two versions of a package, the
+// status is one of "added", "removed" or "modified". The diff is in the unified format,
+// it is omitted for the binary files and the files exceeding the size limits.
+type PackageFileChange struct {
+	Path        string `json:"path"`
+	Status      string `json:"status"`
+	OldSize     int64  `json:"oldSize"`
+	NewSize     int64  `json:"newSize"`
+	Binary      bool   `json:"binary,omitempty"`
+	Diff        string `json:"diff,omitempty"`
+	DiffOmitted bool   `json:"diffOmitted,omitempty"`
+}
+
+// PackageMetaChange holds a metadata field which differs between two versions of a package.
+type PackageMetaChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// PackageSymbolChange holds an exported symbol whose kind or signature is changed.
+type PackageSymbolChange struct {
+	ImportPath   string `json:"importPath"`
+	Receiver     string `json:"receiver,omitempty"`
+	Name         string `json:"name"`
+	OldKind      string `json:"oldKind"`
+	NewKind      string `json:"newKind"`
+	OldSignature string `json:"oldSignature"`
+	NewSignature string `json:"newSignature"`
+}
+
+// PackageChangelog holds the release notes of a package version, extracted from the
+// section of the version in its changelog file.
+type PackageChangelog struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	FileName string `json:"fileName"`
+	Date     string `json:"date,omitempty"`
+	Notes    string `json:"notes"`
+}
+
 // PackageReadme holds the contents of README.
 type PackageReadme struct {
 	Name    string `json:"name"`
@@ -80,3 +340,25 @@ type PackageReadme struct {
 	Size    uint64 `json:"size"`
 	Content string `json:"content"`
 }
+
+// PublishErrorDetails holds all the problems of a failed publish, sent as the details
+// of the error response.
+type PublishErrorDetails struct {
+	Errors   []string             `json:"errors"`
+	Warnings []string             `json:"warnings,omitempty"`
+	Entries  []*ArchiveEntryError `json:"entries,omitempty"`
+}
+
+// PublishJob holds the progress of an asynchronous package publish.
+type PublishJob struct {
+	ID        string               `json:"id"`
+	Status    string               `json:"status"`
+	Stage     string               `json:"stage"`
+	ErrorCode int                  `json:"errorCode,omitempty"`
+	Errors    []string             `json:"errors,omitempty"`
+	Warnings  []string             `json:"warnings,omitempty"`
+	Entries   []*ArchiveEntryError `json:"entries,omitempty"`
+	Package   *PublishedPackage    `json:"package,omitempty"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
@@ -43,3 +43,8 @@ type UserSocialAccountsMutation struct {
 	StackOverflow *string `json:"stackOverflow"`
 	LinkedIn      *string `json:"linkedin"`
 }
+
+// UsernameMutation holds username change data.
+type UsernameMutation struct {
+	Username string `json:"username"`
+}
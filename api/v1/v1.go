@@ -2,9 +2,19 @@ package v1
 
 import (
 	"github.com/gorilla/mux"
+	"gopx.io/gopx-api/api/v1/controller/idempotency"
+	"gopx.io/gopx-api/api/v1/controller/job"
+	"gopx.io/gopx-api/api/v1/controller/pkg"
 	"gopx.io/gopx-api/api/v1/handler"
 )
 
+// RunBackgroundTasks starts the background tasks for API version v1.
+func RunBackgroundTasks() {
+	go pkg.RunReconciler()
+	job.StartWorkers()
+	go idempotency.RunCleaner()
+}
+
 // RegisterRoutes registers the routes for API version v1.
 func RegisterRoutes(r *mux.Router) {
 	r.Path("/users").
@@ -25,7 +35,11 @@ func RegisterRoutes(r *mux.Router) {
 
 	r.Path("/user").
 		Methods("PATCH").
-		HandlerFunc(handler.CurrentUserPATCH)
+		HandlerFunc(handler.Idempotent(handler.CurrentUserPATCH))
+
+	r.Path("/user/username").
+		Methods("PATCH").
+		HandlerFunc(handler.Idempotent(handler.CurrentUserUsernamePATCH))
 
 	r.Path("/user/packages").
 		Methods("GET").
@@ -33,11 +47,23 @@ func RegisterRoutes(r *mux.Router) {
 
 	r.Path("/user/packages").
 		Methods("POST").
-		HandlerFunc(handler.CurrentUserPackagesPOST)
+		HandlerFunc(handler.Idempotent(handler.CurrentUserPackagesPOST))
 
 	r.Path("/user/packages/{packageName}").
 		Methods("DELETE").
-		HandlerFunc(handler.CurrentUserPackagesDELETE)
+		HandlerFunc(handler.Idempotent(handler.CurrentUserPackagesDELETE))
+
+	r.Path("/user/packages/{packageName}").
+		Methods("PATCH").
+		HandlerFunc(handler.Idempotent(handler.CurrentUserPackagesPATCH))
+
+	r.Path("/validate").
+		Methods("POST").
+		HandlerFunc(handler.PackageValidatePOST)
+
+	r.Path("/publish-jobs/{jobID}").
+		Methods("GET").
+		HandlerFunc(handler.PublishJobGET)
 
 	r.Path("/packages").
 		Methods("GET").
@@ -47,6 +73,50 @@ func RegisterRoutes(r *mux.Router) {
 		Methods("GET").
 		HandlerFunc(handler.SinglePackageGET)
 
+	r.Path("/packages/{packageName}/versions/{version}").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/dependencies").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionDependenciesGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/archive").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionArchiveGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/api").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionAPIGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/docs").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionDocsGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/changelog").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionChangelogGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/files").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionFilesGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/files/{path:.+}").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionFileGET)
+
+	r.Path("/packages/{packageName}/@v/{version}.mod").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageVersionModGET)
+
+	r.Path("/packages/{packageName}/compare/{from}...{to}").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageCompareGET)
+
+	r.Path("/packages/{packageName}/dependents").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageDependentsGET)
+
 	r.Path("/packages/{packageName}/readme").
 		Methods("GET").
 		HandlerFunc(handler.SinglePackageReadmeGET)
@@ -55,6 +125,10 @@ func RegisterRoutes(r *mux.Router) {
 		Methods("GET").
 		HandlerFunc(handler.DownloadsGET)
 
+	r.Path("/licenses").
+		Methods("GET").
+		HandlerFunc(handler.LicensesGET)
+
 	r.Path("/versions/{packageName}").
 		Methods("GET").
 		HandlerFunc(handler.SinglePackageVersionsGET)
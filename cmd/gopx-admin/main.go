@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopx.io/gopx-api/api/v1/controller/pkg"
+	"gopx.io/gopx-api/pkg/config"
+	"gopx.io/gopx-api/pkg/controller/storage"
+	"gopx.io/gopx-common/log"
+)
+
+const usage = `Usage: gopx-admin <command>
+
+Commands:
+	verify-archives       Re-verify the stored package archives against their recorded digests
+	normalize-licenses    Rewrite the package licenses published before the normalization as SPDX expressions
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "verify-archives":
+		os.Exit(verifyArchives())
+	case "normalize-licenses":
+		os.Exit(normalizeLicenses())
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// verifyArchives checks every stored archive against its recorded digests
+// and returns a non-zero exit code if any archive is missing or corrupted.
+// Unless the storage is shared, only the archives stored by this instance
+// are checked, the others are not available in the local storage.
+func verifyArchives() int {
+	instanceID := ""
+	if !config.Storage.Shared {
+		instanceID = config.Service.InstanceID
+		log.Info("Verifying the archives stored by instance %s", instanceID)
+	}
+
+	digests, err := pkg.ArchiveDigests(instanceID)
+	if err != nil {
+		log.Error("Error %s", err)
+		return 1
+	}
+
+	failed := 0
+	for _, vd := range digests {
+		sha256Hex, sha512Hex, err := storage.DigestArchive(vd.PackageName, vd.Version)
+		switch {
+		case err != nil:
+			failed++
+			log.Error("%s@%s: %s", vd.PackageName, vd.Version, err)
+		case sha256Hex != vd.SHA256 || sha512Hex != vd.SHA512:
+			failed++
+			log.Error("%s@%s: digest mismatch", vd.PackageName, vd.Version)
+		}
+	}
+
+	log.Info("Verified %d archives, %d failed", len(digests), failed)
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// normalizeLicenses rewrites the package licenses in the canonical SPDX form and
+// returns a non-zero exit code if any license is not a valid expression, those
+// are kept as they are.
+func normalizeLicenses() int {
+	updated, invalid, err := pkg.NormalizeLicenses()
+	for _, v := range invalid {
+		log.Error("Invalid license %s", v)
+	}
+	if err != nil {
+		log.Error("Error %s", err)
+		return 1
+	}
+
+	log.Info("Normalized %d licenses, %d invalid", updated, len(invalid))
+
+	if len(invalid) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"time"
 
+	"gopx.io/gopx-api/api/v1"
 	"gopx.io/gopx-api/pkg/config"
 	"gopx.io/gopx-api/pkg/route"
 	"gopx.io/gopx-common/log"
@@ -21,6 +22,7 @@ func init() {
 }
 
 func main() {
+	v1.RunBackgroundTasks()
 	startServer()
 }
 
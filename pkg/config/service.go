@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"time"
 
 	"gopx.io/gopx-common/log"
@@ -12,6 +13,8 @@ import (
 const ServiceConfigPath = "./config/service.json"
 
 // ServiceConfig represents API service related configurations.
+// The instance ID identifies this instance of the service among the others sharing the
+// same database, it must be stable across restarts and defaults to the host name.
 type ServiceConfig struct {
 	Host         string        `json:"host"`
 	UseHTTP      bool          `json:"useHTTP"`
@@ -23,6 +26,7 @@ type ServiceConfig struct {
 	ReadTimeout  time.Duration `json:"readTimeout"`
 	WriteTimeout time.Duration `json:"writeTimeout"`
 	IdleTimeout  time.Duration `json:"idleTimeout"`
+	InstanceID   string        `json:"instanceID"`
 }
 
 // Service holds loaded API service related configurations.
@@ -37,4 +41,11 @@ func init() {
 	if err != nil {
 		log.Fatal("Error: %s", err)
 	}
+
+	if Service.InstanceID == "" {
+		Service.InstanceID, err = os.Hostname()
+		if err != nil {
+			log.Fatal("Error: %s", err)
+		}
+	}
 }
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"gopx.io/gopx-common/log"
+)
+
+// StorageConfigPath holds local storage related configuration file path.
+const StorageConfigPath = "./config/storage.json"
+
+// StorageConfig represents local storage related configurations.
+// Shared tells that the data directory is shared by all the instances of the service
+// e.g. a network file system, otherwise the stored archives are only available to the
+// instance which wrote them.
+type StorageConfig struct {
+	DataDir string `json:"dataDir"`
+	Shared  bool   `json:"shared"`
+}
+
+// Storage holds loaded local storage related configurations.
+var Storage = new(StorageConfig)
+
+func init() {
+	bytes, err := ioutil.ReadFile(StorageConfigPath)
+	if err != nil {
+		log.Fatal("Error: %s", err)
+	}
+	err = json.Unmarshal(bytes, Storage)
+	if err != nil {
+		log.Fatal("Error: %s", err)
+	}
+}
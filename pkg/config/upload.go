@@ -0,0 +1,44 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"gopx.io/gopx-common/log"
+)
+
+// UploadConfigPath holds package upload related configuration file path.
+const UploadConfigPath = "./config/upload.json"
+
+// UploadConfig represents package upload related configurations.
+// The sizes are in bytes, a per-user size takes precedence over a per-organization
+// size which takes precedence over the default maximum size.
+type UploadConfig struct {
+	MaxPackageSize             int64            `json:"maxPackageSize"`
+	UserMaxPackageSize         map[string]int64 `json:"userMaxPackageSize"`
+	OrganizationMaxPackageSize map[string]int64 `json:"organizationMaxPackageSize"`
+	Archive                    ArchiveConfig    `json:"archive"`
+}
+
+// ArchiveConfig represents the limits on the contents of an uploaded package archive.
+// A zero value means the default limit is used.
+type ArchiveConfig struct {
+	MaxUncompressedSize int64 `json:"maxUncompressedSize"`
+	MaxEntries          int   `json:"maxEntries"`
+	MaxCompressionRatio int64 `json:"maxCompressionRatio"`
+	MaxPathDepth        int   `json:"maxPathDepth"`
+}
+
+// Upload holds loaded package upload related configurations.
+var Upload = new(UploadConfig)
+
+func init() {
+	bytes, err := ioutil.ReadFile(UploadConfigPath)
+	if err != nil {
+		log.Fatal("Error: %s", err)
+	}
+	err = json.Unmarshal(bytes, Upload)
+	if err != nil {
+		log.Fatal("Error: %s", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
 	"gopx.io/gopx-api/pkg/config"
 	"gopx.io/gopx-api/pkg/constants"
 	"gopx.io/gopx-common/log"
@@ -58,3 +59,12 @@ func init() {
 func Conn() *sql.DB {
 	return dbConn
 }
+
+// IsDuplicateEntry checks whether the error is caused by a violation of an unique constraint.
+func IsDuplicateEntry(err error) bool {
+	mErr, ok := errors.Cause(err).(*mysql.MySQLError)
+	return ok && mErr.Number == mysqlErrDupEntry
+}
+
+// mysqlErrDupEntry is the MySQL server error number for a duplicate entry of an unique key.
+const mysqlErrDupEntry = 1062
@@ -8,7 +8,13 @@ import (
 
 // Error handles request which causes any error.
 func Error(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
-	writeResponse(w, statusCode, message)
+	writeResponse(w, statusCode, message, nil)
+}
+
+// ErrorDetails handles request which causes any error, the details are
+// sent along with the message to describe the individual problems.
+func ErrorDetails(w http.ResponseWriter, r *http.Request, statusCode int, message string, details interface{}) {
+	writeResponse(w, statusCode, message, details)
 }
 
 // Error401 handles unauthorized request.
@@ -12,7 +12,8 @@ import (
 )
 
 type errorResponse struct {
-	Message string `json:"message"`
+	Message string      `json:"message"`
+	Errors  interface{} `json:"errors,omitempty"`
 }
 
 func setBasicHeaders(headers http.Header) {
@@ -21,13 +22,13 @@ func setBasicHeaders(headers http.Header) {
 	headers.Set("Access-Control-Allow-Origin", "*")
 }
 
-func responseJSON(message string) ([]byte, error) {
+func responseJSON(message string, details interface{}) ([]byte, error) {
 	buff := bytes.Buffer{}
 	enc := json.NewEncoder(&buff)
 	enc.SetIndent("", "  ")
 	enc.SetEscapeHTML(false)
 
-	resp := errorResponse{Message: message}
+	resp := errorResponse{Message: message, Errors: details}
 	err := enc.Encode(resp)
 	if err != nil {
 		return nil, err
@@ -36,8 +37,8 @@ func responseJSON(message string) ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
-func writeResponse(w http.ResponseWriter, statusCode int, message string) {
-	bytes, err := responseJSON(message)
+func writeResponse(w http.ResponseWriter, statusCode int, message string, details interface{}) {
+	bytes, err := responseJSON(message, details)
 	if err != nil {
 		log.Error("Error: %s", err)
 		bytes = []byte(fmt.Sprintf("\"%s\"\n", message))
@@ -0,0 +1,4 @@
+/*
+Package lock provides keyed locks to serialize mutations of a shared resource.
+*/
+package lock
@@ -0,0 +1,60 @@
+package lock
+
+import (
+	"sync"
+)
+
+// Locker serializes the critical sections which share the same key.
+type Locker interface {
+	// Lock blocks until the lock of the key is acquired and returns
+	// the function which releases it.
+	Lock(key string) (unlock func(), err error)
+}
+
+// Local is an in-process Locker, it only serializes the callers running in the
+// same process. The instances of a multi-instance deployment are not protected
+// from each other by it, only the unique keys of the database guard them.
+type Local struct {
+	mu    sync.Mutex
+	locks map[string]*entry
+}
+
+type entry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewLocal creates a new in-process Locker.
+func NewLocal() *Local {
+	return &Local{locks: map[string]*entry{}}
+}
+
+// Lock acquires the lock of the key.
+func (l *Local) Lock(key string) (unlock func(), err error) {
+	l.mu.Lock()
+	e, ok := l.locks[key]
+	if !ok {
+		e = &entry{}
+		l.locks[key] = e
+	}
+	e.refs++
+	l.mu.Unlock()
+
+	e.mu.Lock()
+
+	var once sync.Once
+	unlock = func() {
+		once.Do(func() {
+			e.mu.Unlock()
+
+			l.mu.Lock()
+			e.refs--
+			if e.refs == 0 {
+				delete(l.locks, key)
+			}
+			l.mu.Unlock()
+		})
+	}
+
+	return unlock, nil
+}
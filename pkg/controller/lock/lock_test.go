@@ -0,0 +1,150 @@
+package lock
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLocalSameKey(t *testing.T) {
+	l := NewLocal()
+
+	const workers = 50
+	var (
+		wg      sync.WaitGroup
+		holders int32
+		counter int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := l.Lock("gopx")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+
+			if n := atomic.AddInt32(&holders, 1); n != 1 {
+				t.Errorf("%d holders of the same key", n)
+			}
+			counter++
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+		}()
+	}
+	wg.Wait()
+
+	if counter != workers {
+		t.Errorf("counter = %d, want %d", counter, workers)
+	}
+	if len(l.locks) != 0 {
+		t.Errorf("%d locks are left after all unlocks", len(l.locks))
+	}
+}
+
+func TestLocalDifferentKeys(t *testing.T) {
+	l := NewLocal()
+
+	unlockA, err := l.Lock("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlockA()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			unlock, err := l.Lock(fmt.Sprintf("b%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			unlock()
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the locks of the other keys are blocked by a held key")
+	}
+}
+
+func TestLocalUnlockTwice(t *testing.T) {
+	l := NewLocal()
+
+	unlock, err := l.Lock("gopx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	unlock()
+
+	unlock, err = l.Lock("gopx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+// TestLocalPublishRace stresses the check-then-insert of a publish under the lock,
+// exactly one of the concurrent publishes of the same version must succeed.
+func TestLocalPublishRace(t *testing.T) {
+	l := NewLocal()
+
+	const (
+		rounds   = 100
+		parallel = 16
+	)
+
+	for r := 0; r < rounds; r++ {
+		published := map[string]bool{}
+		var (
+			wg        sync.WaitGroup
+			succeeded int32
+		)
+
+		for i := 0; i < parallel; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				unlock, err := l.Lock("gopx")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer unlock()
+
+				if published["1.0.0"] {
+					return
+				}
+				time.Sleep(time.Microsecond)
+				published["1.0.0"] = true
+				atomic.AddInt32(&succeeded, 1)
+			}()
+		}
+		wg.Wait()
+
+		if succeeded != 1 {
+			t.Fatalf("round %d: %d publishes succeeded, want 1", r, succeeded)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package storage provides controllers to keep package archives on local storage.
+*/
+package storage
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-api/pkg/config"
+)
+
+// ArchivePath returns the local path of the archive of a package version.
+func ArchivePath(packageName, version string) string {
+	return filepath.Join(packageDir(packageName), version+".tar.gz")
+}
+
+// SaveArchive durably writes the archive of a package version to the local storage.
+// The archive is written to a temp file first and then renamed, so a partially
+// written archive is never visible under the final path.
+func SaveArchive(packageName, version string, data io.Reader) (err error) {
+	dir := packageDir(packageName)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to create package archive directory")
+		return
+	}
+
+	tmpFile, err := ioutil.TempFile(dir, ".upload-")
+	if err != nil {
+		err = errors.Wrap(err, "Failed to create temp archive file")
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	_, err = io.Copy(tmpFile, data)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to write archive data")
+		return
+	}
+
+	err = tmpFile.Sync()
+	if err != nil {
+		err = errors.Wrap(err, "Failed to flush archive data")
+		return
+	}
+
+	err = os.Rename(tmpFile.Name(), ArchivePath(packageName, version))
+	if err != nil {
+		err = errors.Wrap(err, "Failed to move archive to its final path")
+		return
+	}
+
+	return nil
+}
+
+// OpenArchive opens the archive of a package version for reading.
+func OpenArchive(packageName, version string) (f *os.File, err error) {
+	f, err = os.Open(ArchivePath(packageName, version))
+	if err != nil {
+		err = errors.Wrap(err, "Failed to open package archive")
+		return
+	}
+
+	return
+}
+
+// DigestArchive computes the hex encoded SHA-256 and SHA-512 digests of the stored archive of a package version.
+func DigestArchive(packageName, version string) (sha256Hex, sha512Hex string, err error) {
+	f, err := OpenArchive(packageName, version)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	hash256 := sha256.New()
+	hash512 := sha512.New()
+	_, err = io.Copy(io.MultiWriter(hash256, hash512), f)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to read package archive")
+		return
+	}
+
+	return hex.EncodeToString(hash256.Sum(nil)), hex.EncodeToString(hash512.Sum(nil)), nil
+}
+
+// RemoveArchive removes the archive of a package version from the local storage.
+func RemoveArchive(packageName, version string) (err error) {
+	err = os.Remove(ArchivePath(packageName, version))
+	if err != nil && !os.IsNotExist(err) {
+		err = errors.Wrap(err, "Failed to remove package archive")
+		return
+	}
+
+	return nil
+}
+
+// RemovePackage removes all the archives of a package from the local storage.
+func RemovePackage(packageName string) (err error) {
+	err = os.RemoveAll(packageDir(packageName))
+	if err != nil {
+		err = errors.Wrap(err, "Failed to remove package archives")
+		return
+	}
+
+	return nil
+}
+
+func packageDir(packageName string) string {
+	return filepath.Join(config.Storage.DataDir, "archives", packageName)
+}
@@ -17,6 +17,12 @@ import (
 	errorCtrl "gopx.io/gopx-api/pkg/controller/error"
 )
 
+// Error constants
+var (
+	ErrPackageExists   = errors.New("Package version already exists in vcs registry")
+	ErrPackageNotFound = errors.New("Package not found in vcs registry")
+)
+
 // PackageType represents type of package in vcs registry.
 type PackageType int
 
@@ -121,6 +127,10 @@ func RegisterPackage(meta *PackageMeta, data io.Reader) (err error) {
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusConflict {
+		return ErrPackageExists
+	}
+
 	if res.StatusCode != http.StatusCreated {
 		body, err := ioutil.ReadAll(res.Body)
 		if err != nil {
@@ -159,6 +169,10 @@ func DeletePackage(packageName string) (err error) {
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotFound {
+		return ErrPackageNotFound
+	}
+
 	if res.StatusCode != http.StatusNoContent {
 		body, err := ioutil.ReadAll(res.Body)
 		if err != nil {
@@ -178,6 +192,52 @@ func DeletePackage(packageName string) (err error) {
 
 }
 
+// UpdatePackageOwner updates the owner data of a package in vcs registry.
+func UpdatePackageOwner(packageName string, owner *PackageOwner) (err error) {
+	reqPath := fmt.Sprintf("/v1/packages/%s/owner", packageName)
+	reqMethod := http.MethodPut
+
+	ownerBuff := bytes.Buffer{}
+	err = json.NewEncoder(&ownerBuff).Encode(owner)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to encode owner data to JSON")
+		return
+	}
+
+	req, err := http.NewRequest(reqMethod, createReqURL(reqPath), &ownerBuff)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to create the request")
+		return
+	}
+	req.Header.Set("Authorization", createAuthHeader())
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "GoPx API Service")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to send the request")
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to read the response")
+			return err
+		}
+		msg, err := errorCtrl.DecodeErrorMessage(body)
+		if err != nil {
+			err = errors.Wrap(err, "Failed to decode error response")
+			return err
+		}
+
+		return errors.Errorf("Response code %d: %s", res.StatusCode, msg)
+	}
+
+	return
+}
+
 func createReqURL(path string) string {
 	var (
 		vcsServiceHost = os.Getenv(config.Env.GoPxVCSAPIIP)